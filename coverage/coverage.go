@@ -14,8 +14,30 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// ProcessCoverage gets the coverage of {owner}/{repo} after each commit in the past year through codecov's API, saves into mysql
-func ProcessCoverage(db *sql.DB, owner, repo string) error {
+// Option configures ProcessCoverage.
+type Option func(*options)
+
+type options struct {
+	replaceRange bool
+}
+
+// WithReplaceRange makes ProcessCoverage delete the stored coverage of the repo between the first and the last commit fetched
+// before storing the fetched coverage, in the same transaction.
+func WithReplaceRange() Option {
+	return func(o *options) {
+		o.replaceRange = true
+	}
+}
+
+// ProcessCoverage gets the coverage of {owner}/{repo} after each commit in the past year through codecov's API, saves into mysql.
+// The coverage is upserted by the repo and the commit time, so it is safe to process a repo again.
+// The repo is identified by table REPOSITORY, which is shared with package storage, and added to it if it is not there.
+func ProcessCoverage(db *sql.DB, owner, repo string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	log.Printf("Processing %s\n", owner+"/"+repo)
 	client := http.Client{}
 	req, err := http.NewRequest("GET", "https://codecov.io/api/gh/"+owner+"/"+repo+"/branch/master/graphs/commits.json?method=min&agg=day&time=365d&inc=totals&order=asc", strings.NewReader(""))
@@ -27,6 +49,7 @@ func ProcessCoverage(db *sql.DB, owner, repo string) error {
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -55,6 +78,16 @@ func ProcessCoverage(db *sql.DB, owner, repo string) error {
 			}
 		}
 	}()
+	var repoID int64
+	repoID, err = repositoryID(tx, owner, repo)
+	if err != nil {
+		return err
+	}
+	if o.replaceRange {
+		if err = deleteCoverage(tx, repoID, commits.([]interface{})); err != nil {
+			return err
+		}
+	}
 	for _, commit := range commits.([]interface{}) {
 		timestamp := commit.(map[string]interface{})["timestamp"]
 		totals := commit.(map[string]interface{})["totals"]
@@ -68,16 +101,57 @@ func ProcessCoverage(db *sql.DB, owner, repo string) error {
 			err = errT
 			return errT
 		}
-		_, err = tx.Exec("INSERT INTO coverage_timeline(repo_id, time, coverage) SELECT id, ?, ? FROM repository WHERE repo_name = ?", t, coverage, repo)
+		err = insertCoverage(tx, repoID, t, coverage)
 		if err != nil {
 			return err
 		}
 	}
 
-	tx.Commit()
+	if errC := tx.Commit(); errC != nil {
+		return errC
+	}
 	fmt.Println("coverage txn commit")
 
 	log.Printf("Finish %s\n", owner+"/"+repo)
 
 	return nil
 }
+
+// repositoryID inserts owner/repo into table REPOSITORY if it does not exist and returns its ID (not committed)
+func repositoryID(tx *sql.Tx, owner, repo string) (int64, error) {
+	if _, err := tx.Exec(`INSERT IGNORE INTO REPOSITORY(OWNER, REPO_NAME) VALUES(?, ?)`, owner, repo); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := tx.QueryRow(`SELECT ID FROM REPOSITORY WHERE OWNER = ? AND REPO_NAME = ?`, owner, repo).Scan(&id)
+	return id, err
+}
+
+// insertCoverage upserts the coverage of the repository of repoID at t (not committed)
+func insertCoverage(tx *sql.Tx, repoID int64, t time.Time, coverage float64) error {
+	_, err := tx.Exec(`INSERT INTO coverage_timeline(repo_id, time, coverage) VALUES(?, ?, ?)
+		ON DUPLICATE KEY UPDATE coverage = VALUES(coverage)`, repoID, t, coverage)
+	return err
+}
+
+// deleteCoverage deletes the stored coverage of the repository of repoID between the first and the last commit (not committed)
+func deleteCoverage(tx *sql.Tx, repoID int64, commits []interface{}) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	var first, last time.Time
+	for _, commit := range commits {
+		t, err := time.Parse("2006-01-02 15:04:05", commit.(map[string]interface{})["timestamp"].(string))
+		if err != nil {
+			return err
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	_, err := tx.Exec(`DELETE FROM coverage_timeline WHERE repo_id = ? AND time BETWEEN ? AND ?`, repoID, first, last)
+	return err
+}
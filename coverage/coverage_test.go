@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/PingCAP-QE/libs/internal/testdb"
+	"github.com/PingCAP-QE/libs/migration"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -17,4 +20,46 @@ func TestProcessCoverage(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// processing again upserts the same coverage.
+	err = ProcessCoverage(db, "pingcap", "tidb-lightning", WithReplaceRange())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInsertCoverage(t *testing.T) {
+	db := testdb.Open(t)
+	if err := migration.EnsureSchema(db); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Exec(`DELETE FROM coverage_timeline WHERE repo_id IN (SELECT ID FROM REPOSITORY WHERE OWNER = 'test')`)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	repoID, err := repositoryID(tx, "test", "coverage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	for _, coverage := range []float64{70.5, 71.5} {
+		if err := insertCoverage(tx, repoID, at, coverage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the coverage of a repo is upserted by the time.
+	var count int
+	var coverage float64
+	err = db.QueryRow(`SELECT COUNT(*), MAX(coverage) FROM coverage_timeline JOIN REPOSITORY ON REPOSITORY.ID = coverage_timeline.repo_id
+		WHERE REPOSITORY.OWNER = 'test' AND REPOSITORY.REPO_NAME = 'coverage'`).Scan(&count, &coverage)
+	if err != nil || count != 1 || coverage != 71.5 {
+		t.Errorf("coverage is %v of %d rows, %v", coverage, count, err)
+	}
 }
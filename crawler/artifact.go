@@ -17,6 +17,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -37,20 +38,32 @@ func NewGithubClient(token string) *github.Client {
 
 // FetchLatestArtifact fetch the latest artifact in the repo.
 func FetchLatestArtifactUrl(client *github.Client, owner, name string) *url.URL {
+	parsedURL, err := FetchLatestArtifactUrlContext(context.Background(), client, owner, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return parsedURL
+}
+
+// FetchLatestArtifactUrlContext fetch the latest artifact in the repo with ctx.
+func FetchLatestArtifactUrlContext(ctx context.Context, client *github.Client, owner, name string) (*url.URL, error) {
 	pageIndex := 1
 	listOpt := github.ListOptions{
 		Page:    pageIndex,
 		PerPage: 1,
 	}
-	artifacts, _, err := client.Actions.ListArtifacts(context.Background(), owner, name, &listOpt)
+	artifacts, _, err := client.Actions.ListArtifacts(ctx, owner, name, &listOpt)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if len(artifacts.Artifacts) == 0 {
+		return nil, fmt.Errorf("there is no artifact in %v/%v", owner, name)
 	}
-	parsedURL, _, err := client.Actions.DownloadArtifact(context.Background(), owner, name, *artifacts.Artifacts[0].ID, false)
+	parsedURL, _, err := client.Actions.DownloadArtifact(ctx, owner, name, *artifacts.Artifacts[0].ID, false)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return parsedURL
+	return parsedURL, nil
 }
 
 func readZipFile(zf *zip.File) ([]byte, error) {
@@ -64,20 +77,33 @@ func readZipFile(zf *zip.File) ([]byte, error) {
 
 // DownloadAndUnzipArtifact Download And Unzip Artifact by the url from FetchLatestArtifactUrl.
 func DownloadAndUnzipArtifact(url url.URL) [][]byte {
-	resp, err := http.Get(url.String())
+	bytesList, err := DownloadAndUnzipArtifactContext(context.Background(), url)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return bytesList
+}
+
+// DownloadAndUnzipArtifactContext Download And Unzip Artifact by the url from FetchLatestArtifactUrl with ctx.
+func DownloadAndUnzipArtifactContext(ctx context.Context, url url.URL) ([][]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	zipReader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	bytesList := make([][]byte, len(zipReader.File))
@@ -91,5 +117,5 @@ func DownloadAndUnzipArtifact(url url.URL) [][]byte {
 		bytesList[i] = unzippedFileBytes
 	}
 
-	return bytesList
+	return bytesList, nil
 }
@@ -15,62 +15,126 @@ package crawler
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/google/martian/log"
-	"github.com/shurcooL/githubv4"
-	"golang.org/x/oauth2"
 )
 
-var clientV4s []*githubv4.Client
-var clientsIsOpen = false
+// defaultPool is the pool used by InitGithubV4Client and NewGithubV4Client.
+var defaultPool = newPool()
 
 // The Client used for client pool
 type ClientV4 struct {
-	client *githubv4.Client
-	index  int
+	pool *Pool
 }
 
-// NewGithubV4Client return the first Client in the pool
+// NewGithubV4Client return the Client of the default pool.
+// The default pool need to be init by InitGithubV4Client before querying.
 func NewGithubV4Client() ClientV4 {
-	if !clientsIsOpen {
-		panic(fmt.Errorf("clients need to be init before use it , you could init it by InitGithubV4Client"))
-	}
-	return ClientV4{clientV4s[0], 0}
+	return defaultPool.Client()
 }
 
-// initGithubV4Client init the clients pool.
+// initGithubV4Client init the default clients pool.
 func InitGithubV4Client(tokens []string) {
-	clientV4s = make([]*githubv4.Client, len(tokens))
-	for i, token := range tokens {
-		src := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		httpClient := oauth2.NewClient(context.Background(), src)
-		clientV4s[i] = githubv4.NewClient(httpClient)
+	if err := defaultPool.Refresh(tokens); err != nil {
+		log.Errorf("Fail to init the clients pool, because: %v", err)
 	}
-	clientsIsOpen = true
 }
 
-// RefreshGithubV4Client just init client pool again
+// RefreshGithubV4Client replace the tokens of the default clients pool,
+// queries running with the old tokens will not be interrupted.
 func RefreshGithubV4Client(tokens []string) {
 	InitGithubV4Client(tokens)
 }
 
+// TokenStats returns the rate limit budget of every token in the pool.
+func (c ClientV4) TokenStats() []TokenStats {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.TokenStats()
+}
+
 // QueryWithClientsPool package the client pool , you could use it just like client.Query in githubv4 package
+// Every query is sent with the token which has the most remaining budget,
+// and it waits until the earliest reset if all the tokens are exhausted.
+// The errors are returned as *QueryError:
+//   - rate limited tokens are not used until their budget resets,
+//   - unauthorized tokens are quarantined and the query is sent with the other tokens,
+//   - secondary rate limits and transport errors are retried with exponential backoff,
+//   - query errors and not found errors are returned immediately.
 func (c ClientV4) QueryWithClientsPool(ctx context.Context, q interface{}, variables map[string]interface{}) error {
+	if c.pool == nil {
+		return ErrPoolNotInit
+	}
+	scheduler := c.pool.scheduler
+	policy := c.pool.RetryPolicy()
+	retries := 0
+	var lastErr error
 	for {
-		err := c.client.Query(ctx, q, variables)
-		if err != nil {
-			if c.index == len(clientV4s)-1 {
+		token, wait := scheduler.pick(nil)
+		if token == nil {
+			if wait == 0 {
+				if lastErr == nil {
+					return ErrPoolNotInit
+				}
 				log.Errorf("All tokens has been used, but could not stop the steps of errors.")
+				return lastErr
+			}
+			log.Infof("All tokens are exhausted, wait %v until the rate limit resets.", wait)
+			if err := sleepContext(ctx, wait); err != nil {
 				return err
 			}
-			c.index++
-			c.client = clientV4s[c.index]
-		} else {
+			continue
+		}
+
+		err := token.client.Query(ctx, q, variables)
+		if err == nil {
+			if rateLimitQuery, ok := q.(RateLimitQuery); ok {
+				scheduler.update(token, rateLimitQuery.GetRateLimit())
+			} else {
+				scheduler.used(token)
+			}
 			return nil
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		queryErr := classifyError(err)
+		lastErr = queryErr
+		switch queryErr.Kind {
+		case ErrRateLimited:
+			log.Infof("A token is rate limited: %v", err)
+			scheduler.exhaust(token, policy.RateLimitDelay)
+			continue
+		case ErrUnauthorized:
+			log.Errorf("A token is quarantined because: %v", err)
+			scheduler.quarantine(token)
+			continue
+		}
+
+		scheduler.fail(token)
+		if !queryErr.retryable() || retries >= policy.MaxRetries {
+			return queryErr
+		}
+		delay := policy.backoff(retries)
+		retries++
+		log.Infof("Query fails because: %v, retry %d after %v", err, retries, delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
 	}
+}
 
+// sleepContext sleeps for d, it returns the error of ctx if ctx is done before that.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -14,6 +14,7 @@
 package crawler
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -22,6 +23,8 @@ import (
 )
 
 // Issue define issue data fetched from github api v4
+// Labels, Assignees and TimelineItems are fetched 100 items a page,
+// the rest pages are fetched by FetchNestedConnections with IssuePaginations.
 type Issue struct {
 	DatabaseId githubv4.Int
 	Number     githubv4.Int
@@ -29,59 +32,185 @@ type Issue struct {
 		Login     string
 		AvatarURL string `graphql:"avatarUrl(size: 72)"`
 	}
-	Closed    githubv4.Boolean
-	ClosedAt  githubv4.DateTime
-	CreatedAt githubv4.DateTime
-	Labels    struct {
-		Nodes []struct {
-			Name githubv4.String
-		}
-	} `graphql:"labels(first: 100)"`
-
-	Assignees struct {
-		Nodes []struct {
+	Repository struct {
+		Name  githubv4.String
+		Owner struct {
 			Login githubv4.String
-			Email githubv4.String
 		}
-	} `graphql:"assignees(first: 100)"`
-	Title githubv4.String
-	Body  githubv4.String
-
-	TimelineItems struct {
-		Nodes []struct {
-			Typename             string `graphql:"__typename"`
-			CrossReferencedEvent struct {
-				Actor struct {
-					Login githubv4.String
-				}
-				CreatedAt githubv4.DateTime
-			} `graphql:"... on CrossReferencedEvent"`
-			AssignedEvent struct {
-				Actor struct {
-					Login githubv4.String
-				}
-				Assignee struct {
-					User struct {
-						Login githubv4.String
-						Email githubv4.String
-					} `graphql:"... on User"`
-				}
-				CreatedAt githubv4.DateTime
-			} `graphql:"... on AssignedEvent"`
-			UnassignedEvent struct {
-				Actor struct {
-					Login githubv4.String
-				}
-				Assignee struct {
-					User struct {
-						Login githubv4.String
-						Email githubv4.String
-					} `graphql:"... on User"`
-				}
-				CreatedAt githubv4.DateTime
-			} `graphql:"... on UnassignedEvent"`
+	}
+	Closed        githubv4.Boolean
+	ClosedAt      githubv4.DateTime
+	CreatedAt     githubv4.DateTime
+	UpdatedAt     githubv4.DateTime
+	Labels        LabelConnection    `graphql:"labels(first: 100)"`
+	Assignees     AssigneeConnection `graphql:"assignees(first: 100)"`
+	Title         githubv4.String
+	Body          githubv4.String
+	TimelineItems TimelineItemConnection `graphql:"timelineItems(first: 100)"`
+}
+
+// repositoryOr returns the owner and name of the repository of the issue,
+// or the owner and name given if the repository is not fetched.
+func (issue *Issue) repositoryOr(owner, name string) (string, string) {
+	if issue.Repository.Name == "" {
+		return owner, name
+	}
+	return string(issue.Repository.Owner.Login), string(issue.Repository.Name)
+}
+
+// LabelConnection define the labels of an issue
+type LabelConnection struct {
+	Nodes []struct {
+		Name githubv4.String
+	}
+	PageInfo PageInfo
+}
+
+// AssigneeConnection define the assignees of an issue
+type AssigneeConnection struct {
+	Nodes []struct {
+		Login githubv4.String
+		Email githubv4.String
+	}
+	PageInfo PageInfo
+}
+
+// TimelineItemConnection define the timeline items of an issue
+type TimelineItemConnection struct {
+	Nodes    []TimelineItem
+	PageInfo PageInfo
+}
+
+type issueLabelsQuery struct {
+	Repository struct {
+		Issue struct {
+			Labels LabelConnection `graphql:"labels(first: 100, after: $labelsCursor)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q issueLabelsQuery) GetPageInfo() PageInfo {
+	return q.Repository.Issue.Labels.PageInfo
+}
+
+func (q issueLabelsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q issueLabelsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q issueLabelsQuery) GetCursorVariable() string {
+	return "labelsCursor"
+}
+
+type issueAssigneesQuery struct {
+	Repository struct {
+		Issue struct {
+			Assignees AssigneeConnection `graphql:"assignees(first: 100, after: $assigneesCursor)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q issueAssigneesQuery) GetPageInfo() PageInfo {
+	return q.Repository.Issue.Assignees.PageInfo
+}
+
+func (q issueAssigneesQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q issueAssigneesQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q issueAssigneesQuery) GetCursorVariable() string {
+	return "assigneesCursor"
+}
+
+type issueTimelineItemsQuery struct {
+	Repository struct {
+		Issue struct {
+			TimelineItems TimelineItemConnection `graphql:"timelineItems(first: 100, after: $timelineItemsCursor)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q issueTimelineItemsQuery) GetPageInfo() PageInfo {
+	return q.Repository.Issue.TimelineItems.PageInfo
+}
+func (q issueTimelineItemsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q issueTimelineItemsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q issueTimelineItemsQuery) GetCursorVariable() string {
+	return "timelineItemsCursor"
+}
+
+// IssuePaginations returns the paginations of labels, assignees and timeline items of *Issue nodes,
+// owner/name is used for the issues fetched without their repository.
+func IssuePaginations(owner, name string) []NestedPagination {
+	issueVariables := func(node interface{}) map[string]interface{} {
+		issue := node.(*Issue)
+		issueOwner, issueName := issue.repositoryOr(owner, name)
+		return map[string]interface{}{
+			"owner":       githubv4.String(issueOwner),
+			"name":        githubv4.String(issueName),
+			"issueNumber": issue.Number,
 		}
-	} `graphql:"timelineItems(first: 100)"`
+	}
+	return []NestedPagination{
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*Issue).Labels.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &issueLabelsQuery{}, issueVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				labels := &node.(*Issue).Labels
+				labels.Nodes = append(labels.Nodes, q.(issueLabelsQuery).Repository.Issue.Labels.Nodes...)
+				labels.PageInfo = q.GetPageInfo()
+			},
+		},
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*Issue).Assignees.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &issueAssigneesQuery{}, issueVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				assignees := &node.(*Issue).Assignees
+				assignees.Nodes = append(assignees.Nodes, q.(issueAssigneesQuery).Repository.Issue.Assignees.Nodes...)
+				assignees.PageInfo = q.GetPageInfo()
+			},
+		},
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*Issue).TimelineItems.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &issueTimelineItemsQuery{}, issueVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				timelineItems := &node.(*Issue).TimelineItems
+				timelineItems.Nodes = append(timelineItems.Nodes, q.(issueTimelineItemsQuery).Repository.Issue.TimelineItems.Nodes...)
+				timelineItems.PageInfo = q.GetPageInfo()
+			},
+		},
+	}
+}
+
+// fetchRestOfIssues fetch the rest pages of labels, assignees and timeline items of issues.
+func fetchRestOfIssues(ctx context.Context, client ClientV4, owner, name string, issues []*Issue) error {
+	nodes := make([]interface{}, len(issues))
+	for i := range issues {
+		nodes[i] = issues[i]
+	}
+	return FetchNestedConnections(ctx, client, nodes, IssuePaginations(owner, name)...)
 }
 
 // IssueConnection define IssueConnection fetched from github api v4
@@ -95,15 +224,10 @@ type IssueConnection struct {
 
 type issueQuery struct {
 	Repository struct {
-		IssueConnection `graphql:"issues(first: 100, after: $commentsCursor, states:$states, filterBy: {labels:$labels,since: $issueDateTime})"`
+		IssueConnection `graphql:"issues(first: 100, after: $issuesCursor, filterBy: $filterBy, orderBy: $orderBy)"`
 		CreatedAt       githubv4.DateTime
 	} `graphql:"repository(owner: $owner, name: $name)"`
-	RateLimit struct {
-		Limit     githubv4.Int
-		Cost      githubv4.Int
-		Remaining githubv4.Int
-		ResetAt   githubv4.DateTime
-	}
+	RateLimit RateLimit
 }
 
 func (q issueQuery) GetPageInfo() PageInfo {
@@ -114,35 +238,84 @@ func (q issueQuery) GetQuery() Query {
 	return Query(q)
 }
 
-// fetchIssuesByLabelsStates fetch issues by labels & states
-// More info of issues could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/objects#issue
-// If there are empty in labels ,you will not get anything.
-// TODO: find way to make the input labels work like omitempty.
-func fetchIssuesByLabelsStates(client ClientV4,
-	owner, name string, labels []string, states []githubv4.IssueState, since githubv4.DateTime) (*[]Issue, error) {
-	var query issueQuery
+func (q issueQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
 
-	if len(labels) == 0 {
-		log.Errorf("If there are empty in labels ,you will not get anything.")
-		err := fmt.Errorf("if there are empty in labels ,you will not get anything from %v/%v", owner, name)
-		return nil, err
+func (q issueQuery) GetCursorVariable() string {
+	return "issuesCursor"
+}
+
+// IssueFilter define the filter of issues in a repository, the empty fields are not used.
+// More info of the filter could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/input-objects#issuefilters
+type IssueFilter struct {
+	Labels []string
+	// States are both closed and open if it is empty.
+	States []githubv4.IssueState
+	// Since matches issues updated at or after it.
+	Since     githubv4.DateTime
+	Assignee  string
+	CreatedBy string
+	Mentioned string
+	// Milestone is the number of the milestone, "*" for issues with any milestone.
+	Milestone string
+	// OrderBy is the order of issues, the default order of github is used if it is nil.
+	OrderBy *githubv4.IssueOrder
+}
+
+// filterBy returns the IssueFilters input of the filter.
+func (f IssueFilter) filterBy() githubv4.IssueFilters {
+	optionalString := func(s string) *githubv4.String {
+		if s == "" {
+			return nil
+		}
+		return githubv4.NewString(githubv4.String(s))
 	}
 
-	labelsV4 := make([]githubv4.String, len(labels))
-	for i, label := range labels {
-		labelsV4[i] = githubv4.String(label)
+	filterBy := githubv4.IssueFilters{
+		Assignee:  optionalString(f.Assignee),
+		CreatedBy: optionalString(f.CreatedBy),
+		Mentioned: optionalString(f.Mentioned),
+		Milestone: optionalString(f.Milestone),
 	}
+	if len(f.Labels) > 0 {
+		labels := make([]githubv4.String, len(f.Labels))
+		for i, label := range f.Labels {
+			labels[i] = githubv4.String(label)
+		}
+		filterBy.Labels = &labels
+	}
+	states := f.States
+	if len(states) == 0 {
+		states = []githubv4.IssueState{githubv4.IssueStateClosed, githubv4.IssueStateOpen}
+	}
+	filterBy.States = &states
+	if !f.Since.IsZero() {
+		filterBy.Since = githubv4.NewDateTime(f.Since)
+	}
+	return filterBy
+}
 
+// fetchIssuesByLabelsStates fetch issues by labels & states
+// More info of issues could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/objects#issue
+// All the issues are fetched if labels is empty.
+func fetchIssuesByLabelsStates(ctx context.Context, client ClientV4,
+	owner, name string, labels []string, states []githubv4.IssueState, since githubv4.DateTime) (*[]Issue, error) {
+	return fetchIssuesByFilter(ctx, client, owner, name, IssueFilter{Labels: labels, States: states, Since: since})
+}
+
+// fetchIssuesByFilter fetch issues matched by filter.
+func fetchIssuesByFilter(ctx context.Context, client ClientV4, owner, name string, filter IssueFilter) (*[]Issue, error) {
+	var query issueQuery
 	variables := map[string]interface{}{
-		"owner":          githubv4.String(owner),
-		"name":           githubv4.String(name),
-		"labels":         labelsV4,
-		"states":         states,
-		"commentsCursor": (*githubv4.String)(nil),
-		"issueDateTime":  since,
+		"owner":        githubv4.String(owner),
+		"name":         githubv4.String(name),
+		"filterBy":     filter.filterBy(),
+		"orderBy":      filter.OrderBy,
+		"issuesCursor": (*githubv4.String)(nil),
 	}
 
-	queryList, err := FetchAllQueries(client, &query, variables)
+	queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
 	if err != nil {
 		log.Errorf(" fetch issue error")
 		return nil, err
@@ -154,12 +327,23 @@ func fetchIssuesByLabelsStates(client ClientV4,
 		issues = append(issues, issueQueryInstance.Repository.IssueConnection.Nodes...)
 	}
 
+	issuePointers := make([]*Issue, len(issues))
+	for i := range issues {
+		issuePointers[i] = &issues[i]
+	}
+	if err := fetchRestOfIssues(ctx, client, owner, name, issuePointers); err != nil {
+		log.Errorf(" fetch rest of issues error")
+		return nil, err
+	}
+
 	return &issues, nil
 }
 
 // Comment define Comment fetched from github api v4
 type Comment struct {
 	DatabaseId     githubv4.Int
+	CreatedAt      githubv4.DateTime
+	UpdatedAt      githubv4.DateTime
 	Body           string
 	ViewerCanReact bool
 	Author         struct {
@@ -180,12 +364,7 @@ type commentQuery struct {
 			} `graphql:"comments(first: 100, after: $commentsCursor)"`
 		} `graphql:"issue(number: $issueNumber)"`
 	} `graphql:"repository(owner: $repositoryOwner, name: $repositoryName)"`
-	RateLimit struct {
-		Limit     githubv4.Int
-		Cost      githubv4.Int
-		Remaining githubv4.Int
-		ResetAt   githubv4.DateTime
-	}
+	RateLimit RateLimit
 }
 
 func (q commentQuery) GetPageInfo() PageInfo {
@@ -196,18 +375,27 @@ func (q commentQuery) GetQuery() Query {
 	return Query(q)
 }
 
+func (q commentQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
 // fetchCommentsByIssuesNumbers fetch comments by issues number
 // More info of comments could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/interfaces#comment
-func fetchCommentsByIssuesNumbers(client ClientV4, owner, name string, issueNumber int) (*[]Comment, error) {
+func fetchCommentsByIssuesNumbers(ctx context.Context, client ClientV4, owner, name string, issueNumber int) (*[]Comment, error) {
+	return fetchCommentsAfter(ctx, client, owner, name, issueNumber, nil)
+}
+
+// fetchCommentsAfter fetch comments of the issue after the cursor, all comments are fetched if after is nil.
+func fetchCommentsAfter(ctx context.Context, client ClientV4, owner, name string, issueNumber int, after *githubv4.String) (*[]Comment, error) {
 	var query commentQuery
 	variables := map[string]interface{}{
 		"repositoryOwner": githubv4.String(owner),
 		"repositoryName":  githubv4.String(name),
 		"issueNumber":     githubv4.Int(issueNumber),
-		"commentsCursor":  (*githubv4.String)(nil),
+		"commentsCursor":  after,
 	}
 
-	queryList, err := FetchAllQueries(client, &query, variables)
+	queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
 	if err != nil {
 		log.Errorf("fetch comments error")
 		return nil, err
@@ -228,50 +416,278 @@ type IssueWithComments struct {
 	Comments *[]Comment
 }
 
+// issueWithFirstComments define issue fetched with the first page of its comments.
+type issueWithFirstComments struct {
+	Issue
+	Comments struct {
+		Nodes    []Comment
+		PageInfo PageInfo
+	} `graphql:"comments(first: 100)"`
+}
+
+type issueWithCommentsQuery struct {
+	Repository struct {
+		Issues struct {
+			Nodes    []issueWithFirstComments
+			PageInfo PageInfo
+		} `graphql:"issues(first: 100, after: $issuesCursor, filterBy: $filterBy, orderBy: $orderBy)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q issueWithCommentsQuery) GetPageInfo() PageInfo {
+	return q.Repository.Issues.PageInfo
+}
+
+func (q issueWithCommentsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q issueWithCommentsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q issueWithCommentsQuery) GetCursorVariable() string {
+	return "issuesCursor"
+}
+
+// fetchIssuesWithFirstComments fetch issues matched by filter together with the first page of their comments.
+func fetchIssuesWithFirstComments(ctx context.Context, client ClientV4, owner, name string, filter IssueFilter) ([]issueWithFirstComments, error) {
+	var query issueWithCommentsQuery
+	variables := map[string]interface{}{
+		"owner":        githubv4.String(owner),
+		"name":         githubv4.String(name),
+		"filterBy":     filter.filterBy(),
+		"orderBy":      filter.OrderBy,
+		"issuesCursor": (*githubv4.String)(nil),
+	}
+
+	queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
+	if err != nil {
+		log.Errorf(" fetch issue with comments error")
+		return nil, err
+	}
+
+	var issues []issueWithFirstComments
+	for _, query := range queryList {
+		issues = append(issues, query.(issueWithCommentsQuery).Repository.Issues.Nodes...)
+	}
+
+	if err := fetchRestOfIssuesWithFirstComments(ctx, client, owner, name, issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// fetchRestOfIssuesWithFirstComments fetch the rest pages of labels, assignees and timeline items of issues.
+func fetchRestOfIssuesWithFirstComments(ctx context.Context, client ClientV4, owner, name string, issues []issueWithFirstComments) error {
+	issuePointers := make([]*Issue, len(issues))
+	for i := range issues {
+		issuePointers[i] = &issues[i].Issue
+	}
+	if err := fetchRestOfIssues(ctx, client, owner, name, issuePointers); err != nil {
+		log.Errorf(" fetch rest of issues error")
+		return err
+	}
+	return nil
+}
+
+// DefaultConcurrency is the number of issues whose comments are fetched at the same time by default.
+const DefaultConcurrency = 8
+
+// FetchOptions define the options of fetching issues with comments.
+type FetchOptions struct {
+	// Concurrency limits the number of issues whose comments are fetched at the same time,
+	// DefaultConcurrency is used if it is not positive.
+	Concurrency int
+	// Count limits the number of issues fetched, all the issues are fetched if it is not positive.
+	Count int
+	// Progress is called after the comments of each issue are fetched or failed, calls are serialized.
+	Progress func(done, total int)
+	// BatchComments fetches the first 100 comments of issues in the issue queries,
+	// only the comments of issues which have more comments are fetched by extra queries.
+	// It costs much less than fetching comments of each issue by its own queries.
+	BatchComments bool
+}
+
+// IssueError define the error of fetching comments of an issue.
+type IssueError struct {
+	Number int
+	Err    error
+}
+
+func (e *IssueError) Error() string {
+	return fmt.Sprintf("issue %d: %v", e.Number, e.Err)
+}
+
+func (e *IssueError) Unwrap() error {
+	return e.Err
+}
+
 // FetchIssueWithCommentsByLabels fetch issue combined with comments
-// If there are empty in labels ,you will not get anything.
+// All the issues are fetched if labels is empty.
 func FetchIssueWithCommentsByLabels(client ClientV4, owner, name string, labels []string, since githubv4.DateTime, count ...int) (*[]IssueWithComments, []error) {
-	issues, err := fetchIssuesByLabelsStates(client, owner, name, labels,
-		[]githubv4.IssueState{githubv4.IssueStateClosed, githubv4.IssueStateOpen}, since)
-	if err != nil {
-		return nil, []error{err}
+	return FetchIssueWithCommentsByLabelsContext(context.Background(), client, owner, name, labels, since, count...)
+}
+
+// FetchIssueWithCommentsByLabelsContext fetch issue combined with comments with ctx.
+// No more comments will be fetched once ctx is done, and the error of ctx will be returned.
+func FetchIssueWithCommentsByLabelsContext(ctx context.Context, client ClientV4, owner, name string, labels []string, since githubv4.DateTime, count ...int) (*[]IssueWithComments, []error) {
+	var opts FetchOptions
+	if count != nil {
+		opts.Count = count[0]
 	}
+	return FetchIssueWithCommentsByLabelsWithOptions(ctx, client, owner, name, labels, since, opts)
+}
+
+// FetchIssueWithCommentsByLabelsWithOptions fetch issue combined with comments by opts.
+func FetchIssueWithCommentsByLabelsWithOptions(ctx context.Context, client ClientV4, owner, name string, labels []string, since githubv4.DateTime, opts FetchOptions) (*[]IssueWithComments, []error) {
+	return FetchIssueWithComments(ctx, client, owner, name, IssueFilter{Labels: labels, Since: since}, opts)
+}
+
+// FetchIssueWithComments fetch issues matched by filter combined with comments.
+// The issues are returned in the order github returns them, even if the comments of some issues fail to be fetched.
+// Those issues have nil Comments, and their errors are returned as *IssueError.
+func FetchIssueWithComments(ctx context.Context, client ClientV4, owner, name string, filter IssueFilter, opts FetchOptions) (*[]IssueWithComments, []error) {
+	var issues []issueWithFirstComments
+	if opts.BatchComments {
+		var err error
+		issues, err = fetchIssuesWithFirstComments(ctx, client, owner, name, filter)
+		if err != nil {
+			return nil, []error{err}
+		}
+	} else {
+		issuesWithoutComments, err := fetchIssuesByFilter(ctx, client, owner, name, filter)
+		if err != nil {
+			return nil, []error{err}
+		}
+		issues = withoutFirstComments(*issuesWithoutComments)
+	}
+	return combineComments(ctx, client, owner, name, issues, opts)
+}
+
+// withoutFirstComments wraps issues fetched without comments.
+func withoutFirstComments(issues []Issue) []issueWithFirstComments {
+	if issues == nil {
+		return nil
+	}
+	issuesWithFirstComments := make([]issueWithFirstComments, len(issues))
+	for i, issue := range issues {
+		issuesWithFirstComments[i].Issue = issue
+	}
+	return issuesWithFirstComments
+}
+
+// combineComments fetch the comments of issues which are not fetched with issues.
+// If opts.BatchComments is false, issues are considered to be fetched without any comment.
+func combineComments(ctx context.Context, client ClientV4, owner, name string, issues []issueWithFirstComments, opts FetchOptions) (*[]IssueWithComments, []error) {
 	if issues == nil {
 		return nil, nil
 	}
 
-	issuesSize := len(*issues)
-	if count != nil && count[0] < issuesSize {
-		issuesSize = count[0]
+	issuesSize := len(issues)
+	if opts.Count > 0 && opts.Count < issuesSize {
+		issuesSize = opts.Count
 	}
 
 	issueWithComments := make([]IssueWithComments, issuesSize)
-	for i, issue := range (*issues)[0:issuesSize] {
-		issueWithComments[i].Issue = issue
+	var cursors []commentsCursor
+	for i, issue := range issues[0:issuesSize] {
+		issueWithComments[i].Issue = issue.Issue
+		if !opts.BatchComments {
+			cursors = append(cursors, commentsCursor{index: i})
+			continue
+		}
+		comments := issue.Comments.Nodes
+		issueWithComments[i].Comments = &comments
+		if issue.Comments.PageInfo.HasNextPage {
+			cursors = append(cursors, commentsCursor{index: i, after: githubv4.NewString(issue.Comments.PageInfo.EndCursor)})
+		}
 	}
 
+	errs := fetchCommentsOfIssues(ctx, client, owner, name, issueWithComments, cursors, opts)
+	return &issueWithComments, errs
+}
+
+// commentsCursor define the comments of issues[index] to be fetched, which are after the cursor.
+type commentsCursor struct {
+	index int
+	after *githubv4.String
+}
+
+// fetchCommentsOfIssues fetch comments of issues by a bounded number of workers,
+// the comments fetched are appended to the issues, the errors are sorted in the order of issues.
+// Only issues in cursors are fetched, the others are reported as done to Progress at the beginning.
+// owner/name is used for the issues fetched without their repository.
+func fetchCommentsOfIssues(ctx context.Context, client ClientV4, owner, name string, issues []IssueWithComments, cursors []commentsCursor, opts FetchOptions) []error {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > len(cursors) {
+		concurrency = len(cursors)
+	}
+
+	issueErrs := make([]error, len(issues))
 	var mux sync.Mutex
-	var errs []error
-	wg := sync.WaitGroup{}
-	wg.Add(issuesSize)
+	done := len(issues) - len(cursors)
+	if done > 0 && opts.Progress != nil {
+		opts.Progress(done, len(issues))
+	}
+	finish := func(index int, err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		if err != nil {
+			issueErrs[index] = &IssueError{Number: int(issues[index].Number), Err: err}
+		}
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(issues))
+		}
+	}
 
-	for i := range (*issues)[0:issuesSize] {
-		go func(index int) {
+	jobs := make(chan commentsCursor)
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
 			defer wg.Done()
-			comments, err := fetchCommentsByIssuesNumbers(client, owner, name, int(issueWithComments[index].Number))
-			if err != nil {
-				mux.Lock()
-				errs = append(errs, err)
-				mux.Unlock()
+			for job := range jobs {
+				issueOwner, issueName := issues[job.index].repositoryOr(owner, name)
+				comments, err := fetchCommentsAfter(ctx, client, issueOwner, issueName, int(issues[job.index].Number), job.after)
+				if err == nil && issues[job.index].Comments != nil {
+					*comments = append(*issues[job.index].Comments, *comments...)
+				}
+				issues[job.index].Comments = comments
+				finish(job.index, err)
 			}
-			issueWithComments[index].Comments = comments
-		}(i)
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(cursors); next++ {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- cursors[next]:
+		}
 	}
+	close(jobs)
 	wg.Wait()
-	if len(errs) > 0 {
-		return nil, errs
+
+	// The issues not dispatched are failed because of ctx.
+	for ; next < len(cursors); next++ {
+		finish(cursors[next].index, ctx.Err())
+	}
+
+	var errs []error
+	for _, err := range issueErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return &issueWithComments, nil
+	return errs
 }
 
 // The structure of a Query was:
@@ -279,7 +695,9 @@ func FetchIssueWithCommentsByLabels(client ClientV4, owner, name string, labels
 //		the rule of the graphQL data struct could be found in https://docs.github.com/en/free-pro-team@latest/graphql
 //		and https://github.com/shurcooL/githubv4
 // 2. Define variable input to graphQL
+//		the cursor variable is $commentsCursor, unless the Query declares its own by GetCursorVariable
 // 3. Use FetchAllQueries to get Query data list
 // 4. Turn query data list into data struct you want
+//		connections nested in the nodes could be fetched by FetchNestedConnections
 // 5. Output
 // You can read fetchCommentsByIssuesNumbers & fetchIssuesByLabelsStates as examples.
@@ -14,10 +14,17 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -86,16 +93,168 @@ func TestFetchIssueWithCommentsByLabels_Show(t *testing.T) {
 
 	fmt.Println(len(*issueWithComments))
 
+	// all the issues are fetched if there are empty in labels.
 	issueWithComments, errs = FetchIssueWithCommentsByLabels(clientV4, "pingcap", "tidb", []string{}, githubv4.DateTime{}, 10)
 	if errs != nil {
-		if len(errs) != 1 {
-			for _, err := range errs {
-				t.Errorf(err.Error())
+		for _, err := range errs {
+			t.Errorf(err.Error())
+		}
+	} else if len(*issueWithComments) != 10 {
+		t.Errorf("issueWithComments size : %d; expected 10", len(*issueWithComments))
+	}
+}
+
+func TestFetchIssueWithCommentsByLabelsContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("query should not be sent after ctx is canceled")
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs := FetchIssueWithCommentsByLabelsContext(ctx, newTestPool(server.URL).Client(), "pingcap", "tidb", []string{"type/bug"}, githubv4.DateTime{})
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("errs = %v; expected context.Canceled", errs)
+	}
+}
+
+// newFakeGithubServer returns a graphql server which responds the data returned by respond.
+func newFakeGithubServer(t *testing.T, respond func(query string, variables map[string]interface{}) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var in struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.Unmarshal(body, &in); err != nil {
+			t.Error(err)
+			return
+		}
+		fmt.Fprint(w, respond(in.Query, in.Variables))
+	}))
+}
+
+func TestFetchIssueWithCommentsByLabelsWithOptions(t *testing.T) {
+	var running, maxRunning int32
+	var mux sync.Mutex
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "issues(") {
+			return `{"data":{"repository":{"issues":{"nodes":[{"number":1},{"number":2},{"number":3},{"number":4},{"number":5}]}}}}`
+		}
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mux.Lock()
+		if current > maxRunning {
+			maxRunning = current
+		}
+		mux.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		if variables["issueNumber"].(float64) == 3 {
+			return `{"errors":[{"message":"Something went wrong"}]}`
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[{"body":"comment"}]}}}}}`
+	})
+	defer server.Close()
+
+	var progress []int
+	opts := FetchOptions{
+		Concurrency: 2,
+		Progress: func(done, total int) {
+			progress = append(progress, done)
+			if total != 5 {
+				t.Errorf("total = %d; expected 5", total)
+			}
+		},
+	}
+	issues, errs := FetchIssueWithCommentsByLabelsWithOptions(context.Background(), newTestPool(server.URL).Client(),
+		"pingcap", "tidb", []string{"type/bug"}, githubv4.DateTime{}, opts)
+
+	if len(*issues) != 5 {
+		t.Fatalf("issues size = %d; expected 5", len(*issues))
+	}
+	for i, issue := range *issues {
+		if int(issue.Number) != i+1 {
+			t.Errorf("issue %d has number %d", i, issue.Number)
+		}
+		if (issue.Comments == nil) != (issue.Number == 3) {
+			t.Errorf("comments of issue %d is %v", issue.Number, issue.Comments)
+		}
+	}
+	var issueErr *IssueError
+	if len(errs) != 1 || !errors.As(errs[0], &issueErr) || issueErr.Number != 3 {
+		t.Errorf("errs = %v; expected the error of issue 3", errs)
+	}
+	if len(progress) != 5 || progress[4] != 5 {
+		t.Errorf("progress = %v", progress)
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d issues are fetched at the same time; expected at most 2", maxRunning)
+	}
+}
+
+func TestFetchIssueWithCommentsByLabelsBatchComments(t *testing.T) {
+	var commentQueries int32
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "issues(") {
+			return `{"data":{"repository":{"issues":{"nodes":[
+				{"number":1,"comments":{"nodes":[{"body":"a"},{"body":"b"}],"pageInfo":{"endCursor":"c1","hasNextPage":true}}},
+				{"number":2,"comments":{"nodes":[{"body":"c"}],"pageInfo":{"endCursor":"c2","hasNextPage":false}}}]}}}}`
+		}
+		atomic.AddInt32(&commentQueries, 1)
+		if variables["issueNumber"].(float64) != 1 || variables["commentsCursor"] != "c1" {
+			t.Errorf("unexpected comment query with %v", variables)
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[{"body":"d"}]}}}}}`
+	})
+	defer server.Close()
+
+	issues, errs := FetchIssueWithCommentsByLabelsWithOptions(context.Background(), newTestPool(server.URL).Client(),
+		"pingcap", "tidb", []string{"type/bug"}, githubv4.DateTime{}, FetchOptions{BatchComments: true})
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if commentQueries != 1 {
+		t.Errorf("%d comment queries are sent; expected 1", commentQueries)
+	}
+	if len(*(*issues)[0].Comments) != 3 || (*(*issues)[0].Comments)[2].Body != "d" || len(*(*issues)[1].Comments) != 1 {
+		t.Errorf("comments are %v and %v", *(*issues)[0].Comments, *(*issues)[1].Comments)
+	}
+}
+
+func TestFetchIssuesWithNestedConnections(t *testing.T) {
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		switch {
+		case strings.Contains(query, "$labelsCursor"):
+			if variables["labelsCursor"] != "l1" {
+				t.Errorf("labelsCursor = %v; expected l1", variables["labelsCursor"])
 			}
-		} else if errs[0].Error() !=
-			fmt.Errorf("if there are empty in labels ,"+
-				"you will not get anything from %s/%s", "pingcap", "tidb").Error() {
-			t.Error(errs[0])
+			return `{"data":{"repository":{"issue":{"labels":{"nodes":[{"name":"sig/planner"}]}}}}}`
+		case variables["issuesCursor"] == nil:
+			return `{"data":{"repository":{"issues":{"nodes":[{"number":1,
+				"labels":{"nodes":[{"name":"type/bug"}],"pageInfo":{"endCursor":"l1","hasNextPage":true}}}],
+				"pageInfo":{"endCursor":"i1","hasNextPage":true}}}}}`
+		case variables["issuesCursor"] == "i1":
+			return `{"data":{"repository":{"issues":{"nodes":[{"number":2}]}}}}`
 		}
+		t.Errorf("unexpected query %v with %v", query, variables)
+		return `{}`
+	})
+	defer server.Close()
+
+	issues, err := fetchIssuesByLabelsStates(context.Background(), newTestPool(server.URL).Client(),
+		"pingcap", "tidb", []string{"type/bug"}, nil, githubv4.DateTime{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*issues) != 2 {
+		t.Fatalf("issues size = %d; expected 2", len(*issues))
+	}
+	labels := (*issues)[0].Labels.Nodes
+	if len(labels) != 2 || labels[1].Name != "sig/planner" {
+		t.Errorf("labels of issue 1 are %v", labels)
 	}
 }
@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// The kinds of errors returned by github api v4, use errors.Is to check the kind of an error.
+var (
+	ErrRateLimited  = errors.New("github rate limit exceeded")
+	ErrAbuseLimited = errors.New("github secondary rate limit exceeded")
+	ErrUnauthorized = errors.New("github token unauthorized")
+	ErrNotFound     = errors.New("github resource not found")
+	ErrQuery        = errors.New("github query error")
+	ErrTransport    = errors.New("github transport error")
+)
+
+// QueryError is the error returned by QueryWithClientsPool, Kind is one of the kinds of errors above.
+type QueryError struct {
+	Kind error
+	Err  error
+}
+
+func (e *QueryError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// retryable reports whether the query may succeed if it is sent again.
+func (e *QueryError) retryable() bool {
+	return e.Kind == ErrRateLimited || e.Kind == ErrAbuseLimited || e.Kind == ErrTransport
+}
+
+// classifyError returns the QueryError of err returned by githubv4.
+// githubv4 does not expose status codes or error types, so the error messages are matched here.
+func classifyError(err error) *QueryError {
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return queryErr
+	}
+	return &QueryError{Kind: errorKind(err), Err: err}
+}
+
+func errorKind(err error) error {
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse"):
+		return ErrAbuseLimited
+	case strings.Contains(message, "rate limit"):
+		return ErrRateLimited
+	}
+
+	if strings.HasPrefix(message, "non-200 ok status code: ") {
+		status := strings.TrimPrefix(message, "non-200 ok status code: ")
+		switch {
+		case strings.HasPrefix(status, "401"):
+			return ErrUnauthorized
+		case strings.HasPrefix(status, "403"):
+			// 403 without the rate limit message is also returned by the secondary rate limit.
+			return ErrAbuseLimited
+		case strings.HasPrefix(status, "404"):
+			return ErrNotFound
+		case strings.HasPrefix(status, "5"):
+			return ErrTransport
+		default:
+			return ErrQuery
+		}
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return ErrTransport
+	case errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.As(err, &syntaxErr):
+		return ErrTransport
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrTransport
+	case strings.Contains(message, "could not resolve to"):
+		return ErrNotFound
+	}
+	return ErrQuery
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{errors.New("API rate limit exceeded for user ID 1."), ErrRateLimited},
+		{errors.New(`non-200 OK status code: 403 Forbidden body: "You have exceeded a secondary rate limit."`), ErrAbuseLimited},
+		{errors.New(`non-200 OK status code: 401 Unauthorized body: "Bad credentials"`), ErrUnauthorized},
+		{errors.New(`non-200 OK status code: 502 Bad Gateway body: ""`), ErrTransport},
+		{errors.New("Could not resolve to a Repository with the name 'pingcap/none'."), ErrNotFound},
+		{errors.New("Field 'nothing' doesn't exist on type 'Issue'"), ErrQuery},
+		{fmt.Errorf("post: %w", context.DeadlineExceeded), ErrTransport},
+	}
+	for _, c := range cases {
+		err := classifyError(c.err)
+		if !errors.Is(err, c.kind) {
+			t.Errorf("classifyError(%q) = %v; expected %v", c.err, err.Kind, c.kind)
+		}
+	}
+}
+
+type testQuery struct {
+	Viewer struct {
+		Login githubv4.String
+	}
+}
+
+// newTestPool returns a pool whose tokens are the urls of the graphql servers.
+func newTestPool(urls ...string) *Pool {
+	pool := newPool()
+	pool.SetRetryPolicy(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, RateLimitDelay: time.Hour})
+	pool.scheduler.reset(urls, func(url string) *githubv4.Client {
+		return githubv4.NewEnterpriseClient(url, http.DefaultClient)
+	})
+	return pool
+}
+
+func TestQueryWithClientsPoolRetry(t *testing.T) {
+	var calls int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"viewer":{"login":"ok"}}}`)
+	}))
+	defer ok.Close()
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+
+	pool := newTestPool(unauthorized.URL, ok.URL)
+	var q testQuery
+	if err := pool.Client().QueryWithClientsPool(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if q.Viewer.Login != "ok" {
+		t.Errorf("login = %v; expected ok", q.Viewer.Login)
+	}
+	stats := pool.TokenStats()
+	if !stats[0].Quarantined || stats[1].Errors != 1 || stats[1].Queries != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestQueryWithClientsPoolQueryError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"errors":[{"message":"Field 'nothing' doesn't exist on type 'Query'"}]}`)
+	}))
+	defer server.Close()
+
+	pool := newTestPool(server.URL, server.URL+"/")
+	var q testQuery
+	err := pool.Client().QueryWithClientsPool(context.Background(), &q, nil)
+	if !errors.Is(err, ErrQuery) {
+		t.Errorf("err = %v; expected ErrQuery", err)
+	}
+	if calls != 1 {
+		t.Errorf("query is sent %d times; expected 1", calls)
+	}
+}
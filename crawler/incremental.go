@@ -0,0 +1,278 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// Watermark define the progress of an incremental crawl.
+type Watermark struct {
+	// UpdatedAt is the latest update time of the issues crawled.
+	UpdatedAt time.Time
+	// Since and Cursor are the since filter and the cursor of the last page crawled by an unfinished crawl,
+	// the next crawl resumes from them. Cursor is empty if the last crawl is finished.
+	// Since is the UpdatedAt when the unfinished crawl started, the resumed crawl classifies issues and comments by it.
+	Since  time.Time
+	Cursor string
+}
+
+// WatermarkStore persists the watermarks of incremental crawls by their keys.
+type WatermarkStore interface {
+	// Load returns the zero Watermark if there is no watermark of the key.
+	Load(key string) (Watermark, error)
+	Save(key string, watermark Watermark) error
+}
+
+// FileWatermarkStore is a WatermarkStore which saves all the watermarks in a JSON file.
+type FileWatermarkStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileWatermarkStore returns a FileWatermarkStore saving watermarks in path.
+func NewFileWatermarkStore(path string) *FileWatermarkStore {
+	return &FileWatermarkStore{Path: path}
+}
+
+func (s *FileWatermarkStore) load() (map[string]Watermark, error) {
+	watermarks := make(map[string]Watermark)
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return watermarks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &watermarks); err != nil {
+		return nil, err
+	}
+	return watermarks, nil
+}
+
+// Load returns the watermark of the key.
+func (s *FileWatermarkStore) Load(key string) (Watermark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watermarks, err := s.load()
+	if err != nil {
+		return Watermark{}, err
+	}
+	return watermarks[key], nil
+}
+
+// Save saves the watermark of the key, the file is replaced atomically.
+func (s *FileWatermarkStore) Save(key string, watermark Watermark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watermarks, err := s.load()
+	if err != nil {
+		return err
+	}
+	watermarks[key] = watermark
+	data, err := json.MarshalIndent(watermarks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// WatermarkKey returns the key of the watermark of issues in owner/name matched by the filter.
+// Since and OrderBy of the filter are not part of the key because incremental crawls override them.
+func WatermarkKey(owner, name string, filter IssueFilter) string {
+	filter.Since = githubv4.DateTime{}
+	filter.OrderBy = nil
+	data, _ := json.Marshal(filter)
+	return fmt.Sprintf("%s/%s?%s", owner, name, data)
+}
+
+// IncrementalResult define the result of an incremental crawl.
+type IncrementalResult struct {
+	// Issues are the issues created or updated since the last crawl, with all their comments.
+	Issues          []IssueWithComments
+	CreatedIssues   []int
+	UpdatedIssues   []int
+	CreatedComments []int
+	UpdatedComments []int
+	// Watermark is the watermark saved after the crawl.
+	Watermark Watermark
+}
+
+// incrementalOrder orders issues by update time, so the watermark moves forward page by page.
+var incrementalOrder = &githubv4.IssueOrder{Field: githubv4.IssueOrderFieldUpdatedAt, Direction: githubv4.OrderDirectionAsc}
+
+// FetchIssueWithCommentsIncrementally fetch issues matched by filter combined with comments,
+// which are created or updated since the watermark of the crawl saved in store.
+// Issues updated at the watermark are fetched again, storing them again should be idempotent.
+// The watermark is saved after each page of issues is crawled, so an interrupted crawl is resumed by the next call.
+// Created issues and comments are those created after the watermark, the other ones fetched are updated.
+// Comments are only fetched with the issues updated since the watermark. Github updates an issue when a comment
+// is added to it, but not necessarily when a comment is edited, so edited comments of otherwise unchanged issues are missed.
+// opts.Progress is called with the issues of all pages crawled so far, opts.Count is not supported.
+// The issues crawled before an error are returned with the error.
+func FetchIssueWithCommentsIncrementally(ctx context.Context, client ClientV4, store WatermarkStore,
+	owner, name string, filter IssueFilter, opts FetchOptions) (*IncrementalResult, error) {
+	if opts.Count > 0 {
+		return nil, errors.New("count is not supported by incremental crawls")
+	}
+	key := WatermarkKey(owner, name, filter)
+	watermark, err := store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IncrementalResult{Watermark: watermark}
+	// since is also the watermark the fetched issues and comments are classified by,
+	// which is not moved forward by the pages crawled.
+	since := watermark.UpdatedAt
+	var cursor *githubv4.String
+	if watermark.Cursor != "" {
+		since = watermark.Since
+		cursor = githubv4.NewString(githubv4.String(watermark.Cursor))
+	}
+
+	filter.Since = githubv4.DateTime{Time: since}
+	filter.OrderBy = incrementalOrder
+	for {
+		issues, pageInfo, err := fetchIssuePage(ctx, client, owner, name, filter, cursor, opts.BatchComments)
+		if err != nil {
+			return result, err
+		}
+
+		var changed []issueWithFirstComments
+		for _, issue := range issues {
+			if !issue.UpdatedAt.Before(since) {
+				changed = append(changed, issue)
+			}
+		}
+		pageOpts := FetchOptions{
+			Concurrency:   opts.Concurrency,
+			BatchComments: opts.BatchComments,
+		}
+		if opts.Progress != nil {
+			crawled := len(result.Issues)
+			pageOpts.Progress = func(done, total int) {
+				opts.Progress(crawled+done, crawled+total)
+			}
+		}
+		issuesWithComments, errs := combineComments(ctx, client, owner, name, changed, pageOpts)
+		if len(errs) > 0 {
+			return result, errs[0]
+		}
+		if issuesWithComments != nil {
+			result.add(*issuesWithComments, since)
+		}
+
+		if !pageInfo.HasNextPage {
+			result.Watermark.Since = result.Watermark.UpdatedAt
+			result.Watermark.Cursor = ""
+			return result, store.Save(key, result.Watermark)
+		}
+		cursor = githubv4.NewString(pageInfo.EndCursor)
+		result.Watermark.Since = since
+		result.Watermark.Cursor = string(pageInfo.EndCursor)
+		if err := store.Save(key, result.Watermark); err != nil {
+			return result, err
+		}
+	}
+}
+
+// add classifies the issues and comments by the last watermark,
+// and moves the watermark forward to the latest update time of the issues.
+func (r *IncrementalResult) add(issues []IssueWithComments, last time.Time) {
+	for _, issue := range issues {
+		r.Issues = append(r.Issues, issue)
+		if issue.CreatedAt.After(last) || last.IsZero() {
+			r.CreatedIssues = append(r.CreatedIssues, int(issue.Number))
+		} else {
+			r.UpdatedIssues = append(r.UpdatedIssues, int(issue.Number))
+		}
+		if issue.UpdatedAt.After(r.Watermark.UpdatedAt) {
+			r.Watermark.UpdatedAt = issue.UpdatedAt.Time
+		}
+
+		if issue.Comments == nil {
+			continue
+		}
+		for _, comment := range *issue.Comments {
+			switch {
+			case comment.CreatedAt.After(last) || last.IsZero():
+				r.CreatedComments = append(r.CreatedComments, int(comment.DatabaseId))
+			case !comment.UpdatedAt.Before(last):
+				r.UpdatedComments = append(r.UpdatedComments, int(comment.DatabaseId))
+			}
+		}
+	}
+}
+
+// fetchIssuePage fetch a page of issues matched by filter after the cursor,
+// with the first page of comments if withComments is true.
+func fetchIssuePage(ctx context.Context, client ClientV4, owner, name string, filter IssueFilter,
+	after *githubv4.String, withComments bool) ([]issueWithFirstComments, PageInfo, error) {
+	variables := map[string]interface{}{
+		"owner":        githubv4.String(owner),
+		"name":         githubv4.String(name),
+		"filterBy":     filter.filterBy(),
+		"orderBy":      filter.OrderBy,
+		"issuesCursor": after,
+	}
+
+	var issues []issueWithFirstComments
+	var pageInfo PageInfo
+	if withComments {
+		var query issueWithCommentsQuery
+		if err := client.QueryWithClientsPool(ctx, &query, variables); err != nil {
+			return nil, PageInfo{}, err
+		}
+		issues, pageInfo = query.Repository.Issues.Nodes, query.GetPageInfo()
+	} else {
+		var query issueQuery
+		if err := client.QueryWithClientsPool(ctx, &query, variables); err != nil {
+			return nil, PageInfo{}, err
+		}
+		issues, pageInfo = withoutFirstComments(query.Repository.Nodes), query.GetPageInfo()
+	}
+
+	if err := fetchRestOfIssuesWithFirstComments(ctx, client, owner, name, issues); err != nil {
+		return nil, PageInfo{}, err
+	}
+	return issues, pageInfo, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWatermarkStore(t *testing.T) {
+	store := NewFileWatermarkStore(filepath.Join(t.TempDir(), "watermarks.json"))
+	watermark, err := store.Load("pingcap/tidb")
+	if err != nil || !watermark.UpdatedAt.IsZero() {
+		t.Fatalf("Load returns %v, %v; expected zero watermark", watermark, err)
+	}
+
+	expected := Watermark{UpdatedAt: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC), Cursor: "c1"}
+	if err := store.Save("pingcap/tidb", expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("tikv/tikv", Watermark{}); err != nil {
+		t.Fatal(err)
+	}
+	watermark, err = store.Load("pingcap/tidb")
+	if err != nil || !watermark.UpdatedAt.Equal(expected.UpdatedAt) || watermark.Cursor != "c1" {
+		t.Errorf("Load returns %v, %v; expected %v", watermark, err, expected)
+	}
+}
+
+func TestFetchIssueWithCommentsIncrementally(t *testing.T) {
+	// pages of issues ordered by updatedAt, responded by the since filter.
+	pages := map[string][]string{
+		"": {
+			`{"data":{"repository":{"issues":{"nodes":[
+				{"number":1,"createdAt":"2020-09-01T00:00:00Z","updatedAt":"2020-09-02T00:00:00Z"}],
+				"pageInfo":{"endCursor":"p1","hasNextPage":true}}}}}`,
+			`{"data":{"repository":{"issues":{"nodes":[
+				{"number":2,"createdAt":"2020-09-01T00:00:00Z","updatedAt":"2020-09-03T00:00:00Z"}]}}}}`,
+		},
+		"2020-09-03T00:00:00Z": {
+			`{"data":{"repository":{"issues":{"nodes":[
+				{"number":2,"createdAt":"2020-09-01T00:00:00Z","updatedAt":"2020-09-03T00:00:00Z"},
+				{"number":1,"createdAt":"2020-09-01T00:00:00Z","updatedAt":"2020-09-05T00:00:00Z"},
+				{"number":3,"createdAt":"2020-09-06T00:00:00Z","updatedAt":"2020-09-06T00:00:00Z"}]}}}}`,
+		},
+	}
+	// comments of issue 1 in each crawl.
+	comments := []string{
+		`{"data":{"repository":{"issue":{"comments":{"nodes":[
+			{"databaseId":11,"createdAt":"2020-09-02T00:00:00Z","updatedAt":"2020-09-02T00:00:00Z"}]}}}}}`,
+		`{"data":{"repository":{"issue":{"comments":{"nodes":[
+			{"databaseId":11,"createdAt":"2020-09-02T00:00:00Z","updatedAt":"2020-09-04T00:00:00Z"},
+			{"databaseId":12,"createdAt":"2020-09-05T00:00:00Z","updatedAt":"2020-09-05T00:00:00Z"}]}}}}}`,
+	}
+	crawl := 0
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "issues(") {
+			since, _ := variables["filterBy"].(map[string]interface{})["since"].(string)
+			if variables["issuesCursor"] == "p1" {
+				return pages[since][1]
+			}
+			return pages[since][0]
+		}
+		if variables["issueNumber"].(float64) == 1 {
+			return comments[crawl]
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[]}}}}}`
+	})
+	defer server.Close()
+
+	store := NewFileWatermarkStore(filepath.Join(t.TempDir(), "watermarks.json"))
+	client := newTestPool(server.URL).Client()
+	filter := IssueFilter{Labels: []string{"type/bug"}}
+
+	result, err := FetchIssueWithCommentsIncrementally(context.Background(), client, store, "pingcap", "tidb", filter, FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.CreatedIssues, []int{1, 2}) || result.UpdatedIssues != nil {
+		t.Errorf("created issues are %v, updated issues are %v", result.CreatedIssues, result.UpdatedIssues)
+	}
+
+	crawl++
+	result, err = FetchIssueWithCommentsIncrementally(context.Background(), client, store, "pingcap", "tidb", filter, FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// issue 2 updated at the watermark is fetched again.
+	if !reflect.DeepEqual(result.CreatedIssues, []int{3}) || !reflect.DeepEqual(result.UpdatedIssues, []int{2, 1}) {
+		t.Errorf("created issues are %v, updated issues are %v", result.CreatedIssues, result.UpdatedIssues)
+	}
+	if !reflect.DeepEqual(result.CreatedComments, []int{12}) || !reflect.DeepEqual(result.UpdatedComments, []int{11}) {
+		t.Errorf("created comments are %v, updated comments are %v", result.CreatedComments, result.UpdatedComments)
+	}
+
+	watermark, err := store.Load(WatermarkKey("pingcap", "tidb", filter))
+	if err != nil || watermark.Cursor != "" || !watermark.UpdatedAt.Equal(time.Date(2020, 9, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("watermark is %v, %v", watermark, err)
+	}
+}
+
+func TestFetchIssueWithCommentsIncrementallyResume(t *testing.T) {
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "issues(") {
+			if since, _ := variables["filterBy"].(map[string]interface{})["since"].(string); since != "2020-09-01T00:00:00Z" ||
+				variables["issuesCursor"] != "p1" {
+				t.Errorf("crawl is not resumed, since is %v and cursor is %v", since, variables["issuesCursor"])
+			}
+			return `{"data":{"repository":{"issues":{"nodes":[
+				{"number":2,"createdAt":"2020-09-02T00:00:00Z","updatedAt":"2020-09-03T00:00:00Z"},
+				{"number":4,"createdAt":"2020-08-01T00:00:00Z","updatedAt":"2020-09-03T00:00:00Z"}]}}}}`
+		}
+		if variables["issueNumber"].(float64) == 2 {
+			return `{"data":{"repository":{"issue":{"comments":{"nodes":[
+				{"databaseId":21,"createdAt":"2020-09-02T00:00:00Z","updatedAt":"2020-09-04T00:00:00Z"}]}}}}}`
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[]}}}}}`
+	})
+	defer server.Close()
+
+	store := NewFileWatermarkStore(filepath.Join(t.TempDir(), "watermarks.json"))
+	client := newTestPool(server.URL).Client()
+	filter := IssueFilter{Labels: []string{"type/bug"}}
+	// the crawl since 2020-09-01 is interrupted after the first page, whose issues are updated until 2020-09-03.
+	interrupted := Watermark{UpdatedAt: time.Date(2020, 9, 3, 0, 0, 0, 0, time.UTC),
+		Since: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC), Cursor: "p1"}
+	if err := store.Save(WatermarkKey("pingcap", "tidb", filter), interrupted); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []int
+	result, err := FetchIssueWithCommentsIncrementally(context.Background(), client, store, "pingcap", "tidb", filter,
+		FetchOptions{Progress: func(done, total int) { progress = append(progress, done) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// issues are classified by the watermark the interrupted crawl started from.
+	if !reflect.DeepEqual(result.CreatedIssues, []int{2}) || !reflect.DeepEqual(result.UpdatedIssues, []int{4}) {
+		t.Errorf("created issues are %v, updated issues are %v", result.CreatedIssues, result.UpdatedIssues)
+	}
+	if !reflect.DeepEqual(result.CreatedComments, []int{21}) {
+		t.Errorf("created comments are %v", result.CreatedComments)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 2 {
+		t.Errorf("progress is %v", progress)
+	}
+	// the watermark is moved by the issues, not by their comments.
+	if !result.Watermark.UpdatedAt.Equal(interrupted.UpdatedAt) || result.Watermark.Cursor != "" {
+		t.Errorf("watermark is %v", result.Watermark)
+	}
+
+	_, err = FetchIssueWithCommentsIncrementally(context.Background(), client, store, "pingcap", "tidb", filter,
+		FetchOptions{Count: 1})
+	if err == nil {
+		t.Errorf("count is accepted by incremental crawls")
+	}
+}
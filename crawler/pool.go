@@ -0,0 +1,140 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+// ErrPoolNotInit is returned when querying with a pool which has no token.
+var ErrPoolNotInit = errors.New("clients need to be init before use it , you could init it by InitGithubV4Client or NewPool")
+
+// Pool is a pool of github v4 clients, one client for each token.
+// A Pool is safe for concurrent use, and its tokens could be replaced by Refresh at any time.
+type Pool struct {
+	scheduler *scheduler
+
+	mu          sync.RWMutex
+	retryPolicy RetryPolicy
+}
+
+// RetryPolicy define how the transient errors of queries are retried.
+// The delay before the n-th retry is a random duration in [d/2, d), d = min(BaseDelay * 2^n, MaxDelay).
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// RateLimitDelay is how long a rate limited token is not used if github does not tell its reset time.
+	RateLimitDelay time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of new pools.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	BaseDelay:      time.Second,
+	MaxDelay:       time.Minute,
+	RateLimitDelay: time.Minute,
+}
+
+// backoff returns the delay before the n-th retry, n starts from 0.
+func (r RetryPolicy) backoff(n int) time.Duration {
+	delay := r.MaxDelay
+	if n < 32 && r.BaseDelay<<uint(n) < r.MaxDelay {
+		delay = r.BaseDelay << uint(n)
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+func newPool() *Pool {
+	return &Pool{
+		scheduler:   newScheduler(),
+		retryPolicy: DefaultRetryPolicy,
+	}
+}
+
+// NewPool returns a pool of clients authorized by tokens.
+func NewPool(tokens []string) (*Pool, error) {
+	pool := newPool()
+	if err := pool.Refresh(tokens); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Client returns the Client which sends queries with the tokens of the pool.
+func (p *Pool) Client() ClientV4 {
+	return ClientV4{p}
+}
+
+// Refresh replaces the tokens of the pool.
+// The budget of tokens kept in the pool is not lost, and the queries running with removed tokens will not be interrupted.
+func (p *Pool) Refresh(tokens []string) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("there is no token to init the clients pool")
+	}
+	seen := make(map[string]bool, len(tokens))
+	uniqueTokens := make([]string, 0, len(tokens))
+	for i, token := range tokens {
+		if token == "" {
+			return fmt.Errorf("the token %d is empty", i)
+		}
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		uniqueTokens = append(uniqueTokens, token)
+	}
+
+	p.scheduler.reset(uniqueTokens, newClientV4)
+	return nil
+}
+
+// SetRetryPolicy replaces the RetryPolicy of the pool.
+func (p *Pool) SetRetryPolicy(policy RetryPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retryPolicy = policy
+}
+
+// RetryPolicy returns the RetryPolicy of the pool.
+func (p *Pool) RetryPolicy() RetryPolicy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.retryPolicy
+}
+
+// TokenStats returns the rate limit budget of every token in the pool.
+func (p *Pool) TokenStats() []TokenStats {
+	return p.scheduler.stats()
+}
+
+// newClientV4 new githubv4 client by github token.
+func newClientV4(token string) *githubv4.Client {
+	src := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	httpClient := oauth2.NewClient(context.Background(), src)
+	return githubv4.NewClient(httpClient)
+}
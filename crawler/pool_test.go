@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func TestNewPool(t *testing.T) {
+	if _, err := NewPool(nil); err == nil {
+		t.Errorf("NewPool without tokens should fail")
+	}
+	if _, err := NewPool([]string{"a", ""}); err == nil {
+		t.Errorf("NewPool with an empty token should fail")
+	}
+
+	pool, err := NewPool([]string{"a", "b", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.TokenStats()) != 2 {
+		t.Errorf("pool has %d tokens; expected 2", len(pool.TokenStats()))
+	}
+
+	var client ClientV4
+	if err := client.QueryWithClientsPool(context.Background(), nil, nil); err != ErrPoolNotInit {
+		t.Errorf("query without pool returns %v; expected ErrPoolNotInit", err)
+	}
+}
+
+func TestPoolRefreshKeepsBudget(t *testing.T) {
+	pool, err := NewPool([]string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetAt := githubv4.DateTime{Time: time.Now().Add(time.Hour)}
+	pool.scheduler.update(pool.scheduler.tokens[1], RateLimit{Limit: 5000, Cost: 1, Remaining: 42, ResetAt: resetAt})
+
+	if err := pool.Refresh([]string{"b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	stats := pool.TokenStats()
+	if len(stats) != 2 || stats[0].Remaining != 42 || stats[0].Index != 0 || stats[1].Remaining != defaultRateLimit {
+		t.Errorf("stats after refresh is %+v", stats)
+	}
+}
+
+func TestPoolConcurrentRefresh(t *testing.T) {
+	pool, err := NewPool([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := pool.Refresh([]string{"a", "b"}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if token, _ := pool.scheduler.pick(nil); token == nil {
+				t.Error("pick returns no token")
+			}
+			pool.TokenStats()
+		}()
+	}
+	wg.Wait()
+}
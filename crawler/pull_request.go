@@ -0,0 +1,420 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+
+	"github.com/google/martian/log"
+	"github.com/shurcooL/githubv4"
+)
+
+// PullRequest define pull request data fetched from github api v4
+// More info of pull requests could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/objects#pullrequest
+// Labels, Reviews, Commits and ClosingIssuesReferences are fetched page by page,
+// the rest pages are fetched by FetchNestedConnections with PullRequestPaginations.
+type PullRequest struct {
+	DatabaseId githubv4.Int
+	Number     githubv4.Int
+	Author     struct {
+		Login string
+	}
+	Repository struct {
+		Name  githubv4.String
+		Owner struct {
+			Login githubv4.String
+		}
+	}
+	Title          githubv4.String
+	Body           githubv4.String
+	State          githubv4.PullRequestState
+	Merged         githubv4.Boolean
+	MergedAt       githubv4.DateTime
+	Closed         githubv4.Boolean
+	ClosedAt       githubv4.DateTime
+	CreatedAt      githubv4.DateTime
+	UpdatedAt      githubv4.DateTime
+	BaseRefName    githubv4.String
+	HeadRefName    githubv4.String
+	ReviewDecision githubv4.PullRequestReviewDecision
+
+	Labels                  LabelConnection          `graphql:"labels(first: 100)"`
+	Reviews                 ReviewConnection         `graphql:"reviews(first: 50)"`
+	Commits                 CommitConnection         `graphql:"commits(first: 100)"`
+	ClosingIssuesReferences IssueReferenceConnection `graphql:"closingIssuesReferences(first: 25)"`
+}
+
+// ReviewConnection define the reviews of a pull request
+type ReviewConnection struct {
+	Nodes    []Review
+	PageInfo PageInfo
+}
+
+// Review define a review of a pull request, its comments are fetched 50 items a page,
+// the rest pages are fetched by FetchNestedConnections with ReviewCommentsPagination.
+type Review struct {
+	ID     githubv4.ID
+	Author struct {
+		Login string
+	}
+	State       githubv4.PullRequestReviewState
+	Body        githubv4.String
+	SubmittedAt githubv4.DateTime
+	Comments    ReviewCommentConnection `graphql:"comments(first: 50)"`
+}
+
+// ReviewCommentConnection define the comments of a review
+type ReviewCommentConnection struct {
+	Nodes []struct {
+		DatabaseId githubv4.Int
+		Author     struct {
+			Login string
+		}
+		Body      githubv4.String
+		Path      githubv4.String
+		CreatedAt githubv4.DateTime
+	}
+	PageInfo PageInfo
+}
+
+// CommitConnection define the commits of a pull request
+type CommitConnection struct {
+	Nodes []struct {
+		Commit struct {
+			Oid             githubv4.GitObjectID
+			MessageHeadline githubv4.String
+			CommittedDate   githubv4.DateTime
+			Author          struct {
+				Name  githubv4.String
+				Email githubv4.String
+				User  struct {
+					Login githubv4.String
+				}
+			}
+		}
+	}
+	PageInfo PageInfo
+}
+
+// IssueReferenceConnection define the issues which will be closed by a pull request, such as "close #123"
+type IssueReferenceConnection struct {
+	Nodes []struct {
+		Number     githubv4.Int
+		Repository struct {
+			Name  githubv4.String
+			Owner struct {
+				Login githubv4.String
+			}
+		}
+	}
+	PageInfo PageInfo
+}
+
+type pullRequestQuery struct {
+	Repository struct {
+		PullRequests struct {
+			Nodes    []PullRequest
+			PageInfo PageInfo
+		} `graphql:"pullRequests(first: 25, after: $pullRequestsCursor, states: $states, labels: $labels, baseRefName: $baseRefName)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q pullRequestQuery) GetPageInfo() PageInfo {
+	return q.Repository.PullRequests.PageInfo
+}
+
+func (q pullRequestQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q pullRequestQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q pullRequestQuery) GetCursorVariable() string {
+	return "pullRequestsCursor"
+}
+
+type pullRequestLabelsQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Labels LabelConnection `graphql:"labels(first: 100, after: $labelsCursor)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q pullRequestLabelsQuery) GetPageInfo() PageInfo {
+	return q.Repository.PullRequest.Labels.PageInfo
+}
+
+func (q pullRequestLabelsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q pullRequestLabelsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q pullRequestLabelsQuery) GetCursorVariable() string {
+	return "labelsCursor"
+}
+
+type pullRequestReviewsQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Reviews ReviewConnection `graphql:"reviews(first: 50, after: $reviewsCursor)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q pullRequestReviewsQuery) GetPageInfo() PageInfo {
+	return q.Repository.PullRequest.Reviews.PageInfo
+}
+
+func (q pullRequestReviewsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q pullRequestReviewsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q pullRequestReviewsQuery) GetCursorVariable() string {
+	return "reviewsCursor"
+}
+
+type pullRequestCommitsQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Commits CommitConnection `graphql:"commits(first: 100, after: $commitsCursor)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q pullRequestCommitsQuery) GetPageInfo() PageInfo {
+	return q.Repository.PullRequest.Commits.PageInfo
+}
+
+func (q pullRequestCommitsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q pullRequestCommitsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q pullRequestCommitsQuery) GetCursorVariable() string {
+	return "commitsCursor"
+}
+
+type pullRequestClosingIssuesQuery struct {
+	Repository struct {
+		PullRequest struct {
+			ClosingIssuesReferences IssueReferenceConnection `graphql:"closingIssuesReferences(first: 100, after: $closingIssuesCursor)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+	RateLimit RateLimit
+}
+
+func (q pullRequestClosingIssuesQuery) GetPageInfo() PageInfo {
+	return q.Repository.PullRequest.ClosingIssuesReferences.PageInfo
+}
+
+func (q pullRequestClosingIssuesQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q pullRequestClosingIssuesQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q pullRequestClosingIssuesQuery) GetCursorVariable() string {
+	return "closingIssuesCursor"
+}
+
+type reviewCommentsQuery struct {
+	Node struct {
+		Review struct {
+			Comments ReviewCommentConnection `graphql:"comments(first: 100, after: $commentsCursor)"`
+		} `graphql:"... on PullRequestReview"`
+	} `graphql:"node(id: $reviewId)"`
+	RateLimit RateLimit
+}
+
+func (q reviewCommentsQuery) GetPageInfo() PageInfo {
+	return q.Node.Review.Comments.PageInfo
+}
+
+func (q reviewCommentsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q reviewCommentsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+// PullRequestPaginations returns the paginations of labels, reviews, commits and closing issues of *PullRequest nodes.
+func PullRequestPaginations() []NestedPagination {
+	pullRequestVariables := func(node interface{}) map[string]interface{} {
+		pullRequest := node.(*PullRequest)
+		return map[string]interface{}{
+			"owner":  pullRequest.Repository.Owner.Login,
+			"name":   pullRequest.Repository.Name,
+			"number": pullRequest.Number,
+		}
+	}
+	return []NestedPagination{
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*PullRequest).Labels.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &pullRequestLabelsQuery{}, pullRequestVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				labels := &node.(*PullRequest).Labels
+				labels.Nodes = append(labels.Nodes, q.(pullRequestLabelsQuery).Repository.PullRequest.Labels.Nodes...)
+				labels.PageInfo = q.GetPageInfo()
+			},
+		},
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*PullRequest).Reviews.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &pullRequestReviewsQuery{}, pullRequestVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				reviews := &node.(*PullRequest).Reviews
+				reviews.Nodes = append(reviews.Nodes, q.(pullRequestReviewsQuery).Repository.PullRequest.Reviews.Nodes...)
+				reviews.PageInfo = q.GetPageInfo()
+			},
+		},
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*PullRequest).Commits.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &pullRequestCommitsQuery{}, pullRequestVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				commits := &node.(*PullRequest).Commits
+				commits.Nodes = append(commits.Nodes, q.(pullRequestCommitsQuery).Repository.PullRequest.Commits.Nodes...)
+				commits.PageInfo = q.GetPageInfo()
+			},
+		},
+		{
+			PageInfo: func(node interface{}) PageInfo { return node.(*PullRequest).ClosingIssuesReferences.PageInfo },
+			NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+				return &pullRequestClosingIssuesQuery{}, pullRequestVariables(node)
+			},
+			Append: func(node interface{}, q Query) {
+				issues := &node.(*PullRequest).ClosingIssuesReferences
+				issues.Nodes = append(issues.Nodes, q.(pullRequestClosingIssuesQuery).Repository.PullRequest.ClosingIssuesReferences.Nodes...)
+				issues.PageInfo = q.GetPageInfo()
+			},
+		},
+	}
+}
+
+// ReviewCommentsPagination returns the pagination of comments of *Review nodes.
+func ReviewCommentsPagination() NestedPagination {
+	return NestedPagination{
+		PageInfo: func(node interface{}) PageInfo { return node.(*Review).Comments.PageInfo },
+		NewQuery: func(node interface{}) (Query, map[string]interface{}) {
+			return &reviewCommentsQuery{}, map[string]interface{}{"reviewId": node.(*Review).ID}
+		},
+		Append: func(node interface{}, q Query) {
+			comments := &node.(*Review).Comments
+			comments.Nodes = append(comments.Nodes, q.(reviewCommentsQuery).Node.Review.Comments.Nodes...)
+			comments.PageInfo = q.GetPageInfo()
+		},
+	}
+}
+
+// PullRequestFilter define the filter of pull requests in a repository, the empty fields are not used.
+type PullRequestFilter struct {
+	// States are all the states if it is empty.
+	States      []githubv4.PullRequestState
+	Labels      []string
+	BaseRefName string
+}
+
+// FetchPullRequests fetch pull requests matched by filter with their labels, reviews, review comments, commits and closing issues.
+func FetchPullRequests(ctx context.Context, client ClientV4, owner, name string, filter PullRequestFilter) (*[]PullRequest, error) {
+	var query pullRequestQuery
+	variables := map[string]interface{}{
+		"owner":              githubv4.String(owner),
+		"name":               githubv4.String(name),
+		"pullRequestsCursor": (*githubv4.String)(nil),
+		"states":             (*[]githubv4.PullRequestState)(nil),
+		"labels":             (*[]githubv4.String)(nil),
+		"baseRefName":        (*githubv4.String)(nil),
+	}
+	if len(filter.States) > 0 {
+		variables["states"] = &filter.States
+	}
+	if len(filter.Labels) > 0 {
+		labels := make([]githubv4.String, len(filter.Labels))
+		for i, label := range filter.Labels {
+			labels[i] = githubv4.String(label)
+		}
+		variables["labels"] = &labels
+	}
+	if filter.BaseRefName != "" {
+		variables["baseRefName"] = githubv4.NewString(githubv4.String(filter.BaseRefName))
+	}
+
+	queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
+	if err != nil {
+		log.Errorf(" fetch pull request error")
+		return nil, err
+	}
+
+	var pullRequests []PullRequest
+	for _, query := range queryList {
+		pullRequests = append(pullRequests, query.(pullRequestQuery).Repository.PullRequests.Nodes...)
+	}
+
+	nodes := make([]interface{}, len(pullRequests))
+	for i := range pullRequests {
+		nodes[i] = &pullRequests[i]
+	}
+	if err := FetchNestedConnections(ctx, client, nodes, PullRequestPaginations()...); err != nil {
+		log.Errorf(" fetch rest of pull requests error")
+		return nil, err
+	}
+
+	var reviews []interface{}
+	for i := range pullRequests {
+		for j := range pullRequests[i].Reviews.Nodes {
+			reviews = append(reviews, &pullRequests[i].Reviews.Nodes[j])
+		}
+	}
+	if err := FetchNestedConnections(ctx, client, reviews, ReviewCommentsPagination()); err != nil {
+		log.Errorf(" fetch rest of review comments error")
+		return nil, err
+	}
+
+	return &pullRequests, nil
+}
+
+// ClosingIssueNumbers returns the numbers of issues in the same repository which will be closed by the pull request.
+func (pr *PullRequest) ClosingIssueNumbers() []int {
+	var numbers []int
+	for _, issue := range pr.ClosingIssuesReferences.Nodes {
+		if issue.Repository.Name == pr.Repository.Name && issue.Repository.Owner.Login == pr.Repository.Owner.Login {
+			numbers = append(numbers, int(issue.Number))
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func TestFetchPullRequests(t *testing.T) {
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		switch {
+		case strings.Contains(query, "pullRequests("):
+			if variables["baseRefName"] != "master" || variables["labels"] != nil {
+				t.Errorf("variables = %v", variables)
+			}
+			return `{"data":{"repository":{"pullRequests":{"nodes":[{
+				"number":10,"state":"MERGED","merged":true,"mergedAt":"2020-10-02T00:00:00Z","baseRefName":"master",
+				"repository":{"name":"tidb","owner":{"login":"pingcap"}},
+				"reviews":{"nodes":[{"id":"R1","state":"APPROVED",
+					"comments":{"nodes":[{"body":"lgtm"}],"pageInfo":{"endCursor":"c1","hasNextPage":true}}}]},
+				"commits":{"nodes":[{"commit":{"oid":"abc"}}]},
+				"closingIssuesReferences":{"nodes":[
+					{"number":1,"repository":{"name":"tidb","owner":{"login":"pingcap"}}},
+					{"number":2,"repository":{"name":"tikv","owner":{"login":"tikv"}}}]}}]}}}}`
+		case strings.Contains(query, "node(id: $reviewId)"):
+			if variables["reviewId"] != "R1" || variables["commentsCursor"] != "c1" {
+				t.Errorf("variables = %v", variables)
+			}
+			return `{"data":{"node":{"comments":{"nodes":[{"body":"nit"}]}}}}`
+		}
+		t.Errorf("unexpected query %v", query)
+		return `{}`
+	})
+	defer server.Close()
+
+	pullRequests, err := FetchPullRequests(context.Background(), newTestPool(server.URL).Client(), "pingcap", "tidb",
+		PullRequestFilter{BaseRefName: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*pullRequests) != 1 {
+		t.Fatalf("pull requests size = %d; expected 1", len(*pullRequests))
+	}
+	pr := (*pullRequests)[0]
+	if pr.State != githubv4.PullRequestStateMerged || pr.MergedAt.IsZero() || len(pr.Commits.Nodes) != 1 {
+		t.Errorf("pull request = %+v", pr)
+	}
+	if comments := pr.Reviews.Nodes[0].Comments.Nodes; len(comments) != 2 || comments[1].Body != "nit" {
+		t.Errorf("review comments = %v", comments)
+	}
+	if numbers := pr.ClosingIssueNumbers(); len(numbers) != 1 || numbers[0] != 1 {
+		t.Errorf("closing issues = %v; expected [1]", numbers)
+	}
+}
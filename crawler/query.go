@@ -21,23 +21,66 @@ import (
 	"github.com/shurcooL/githubv4"
 )
 
+// PageInfo define the pageInfo of a connection fetched from github api v4
 type PageInfo struct {
 	EndCursor   githubv4.String
 	HasNextPage bool
 }
 
+// Query define a query of a connection which could be fetched page by page by FetchAllQueries
 type Query interface {
 	GetPageInfo() PageInfo
 	GetQuery() Query
 }
 
+// defaultCursorVariable is the cursor variable of queries which do not implement CursorQuery.
+const defaultCursorVariable = "commentsCursor"
+
+// CursorQuery is a Query which declares the name of its cursor variable,
+// the queries not implementing it use $commentsCursor.
+type CursorQuery interface {
+	GetCursorVariable() string
+}
+
+// cursorVariable returns the name of the cursor variable of q.
+func cursorVariable(q Query) string {
+	if cursorQuery, ok := q.(CursorQuery); ok {
+		return cursorQuery.GetCursorVariable()
+	}
+	return defaultCursorVariable
+}
+
+// RateLimit define the rateLimit of the token fetched from github api v4
+// More info could be found in https://docs.github.com/en/free-pro-team@latest/graphql/overview/resource-limitations
+type RateLimit struct {
+	Limit     githubv4.Int
+	Cost      githubv4.Int
+	Remaining githubv4.Int
+	ResetAt   githubv4.DateTime
+}
+
+// RateLimitQuery is a Query which also fetches the rateLimit,
+// the client pool uses it to choose the token for the next query.
+type RateLimitQuery interface {
+	GetRateLimit() RateLimit
+}
+
 // FetchAllQueries just travel all the query among pages.
 // You must input a Query pointer just like it used in fetchIssuesByLabelsStates : FetchAllQueries(client,&query,variables),
 // because query of client need a pointer query input.
 func FetchAllQueries(client ClientV4, q Query, variables map[string]interface{}) ([]Query, error) {
+	return FetchAllQueriesContext(context.Background(), client, q, variables)
+}
+
+// FetchAllQueriesContext just travel all the query among pages with ctx.
+func FetchAllQueriesContext(ctx context.Context, client ClientV4, q Query, variables map[string]interface{}) ([]Query, error) {
 	var queryList []Query
 	for {
-		err := client.QueryWithClientsPool(context.Background(), q, variables)
+		// Every page is decoded into a zero query, so fields missing in the page are not left from the last page.
+		if v := reflect.ValueOf(q); v.Kind() == reflect.Ptr {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+		err := client.QueryWithClientsPool(ctx, q, variables)
 		if err != nil {
 			log.Errorf("Fail to fetch query %v, because: %v", reflect.TypeOf(q), err)
 			return nil, err
@@ -47,7 +90,43 @@ func FetchAllQueries(client ClientV4, q Query, variables map[string]interface{})
 		if !q.GetPageInfo().HasNextPage {
 			break
 		}
-		variables["commentsCursor"] = githubv4.NewString(q.GetPageInfo().EndCursor)
+		variables[cursorVariable(q)] = githubv4.NewString(q.GetPageInfo().EndCursor)
 	}
 	return queryList, nil
 }
+
+// NestedPagination define how to fetch the rest pages of a connection nested in a node,
+// such as the labels of an issue fetched by the issues connection.
+type NestedPagination struct {
+	// PageInfo returns the page info of the connection in the node.
+	PageInfo func(node interface{}) PageInfo
+	// NewQuery returns the Query pointer and its variables to fetch the pages after the cursor of the connection in the node,
+	// the cursor variable of the Query is set by FetchNestedConnections.
+	NewQuery func(node interface{}) (Query, map[string]interface{})
+	// Append appends the nodes of the connection in q to the node.
+	Append func(node interface{}, q Query)
+}
+
+// FetchNestedConnections fetch the rest pages of the nested connections of each node,
+// nodes must be pointers because they are modified by Append.
+func FetchNestedConnections(ctx context.Context, client ClientV4, nodes []interface{}, paginations ...NestedPagination) error {
+	for _, node := range nodes {
+		for _, pagination := range paginations {
+			pageInfo := pagination.PageInfo(node)
+			if !pageInfo.HasNextPage {
+				continue
+			}
+
+			q, variables := pagination.NewQuery(node)
+			variables[cursorVariable(q)] = githubv4.NewString(pageInfo.EndCursor)
+			queryList, err := FetchAllQueriesContext(ctx, client, q, variables)
+			if err != nil {
+				return err
+			}
+			for _, query := range queryList {
+				pagination.Append(node, query)
+			}
+		}
+	}
+	return nil
+}
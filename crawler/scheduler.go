@@ -0,0 +1,223 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// defaultRateLimit is the budget assumed for a token before github tells us its real one.
+const defaultRateLimit = 5000
+
+// rateLimitWindow is how often the budget of a token resets by github.
+const rateLimitWindow = time.Hour
+
+// TokenStats define the rate limit budget of a token in the client pool.
+type TokenStats struct {
+	Index     int
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+	// Cost is the total cost of the queries sent with this token.
+	Cost    int
+	Queries int
+	Errors  int
+	// Quarantined tokens are unauthorized by github and will not be used any more.
+	Quarantined bool
+}
+
+type tokenBudget struct {
+	token    string
+	client   *githubv4.Client
+	stats    TokenStats
+	lastCost int
+}
+
+// remaining returns the budget could be used at now.
+func (t *tokenBudget) remaining(now time.Time) int {
+	if !t.stats.ResetAt.IsZero() && !now.Before(t.stats.ResetAt) {
+		return t.stats.Limit
+	}
+	return t.stats.Remaining
+}
+
+// exhausted reports whether the next query is likely to be rejected by the rate limit.
+func (t *tokenBudget) exhausted(now time.Time) bool {
+	cost := t.lastCost
+	if cost < 1 {
+		cost = 1
+	}
+	return t.remaining(now) < cost
+}
+
+// scheduler picks the token with the most remaining budget for each query.
+type scheduler struct {
+	mu     sync.Mutex
+	tokens []*tokenBudget
+	now    func() time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{now: time.Now}
+}
+
+// reset replaces the tokens of the scheduler, the budget of the tokens kept is not lost.
+// newClient is called for every token which is not in the scheduler yet.
+func (s *scheduler) reset(tokens []string, newClient func(token string) *githubv4.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make(map[string]*tokenBudget, len(s.tokens))
+	for _, token := range s.tokens {
+		kept[token.token] = token
+	}
+
+	s.tokens = make([]*tokenBudget, 0, len(tokens))
+	for _, token := range tokens {
+		budget, ok := kept[token]
+		if !ok {
+			budget = &tokenBudget{
+				token:  token,
+				client: newClient(token),
+				stats: TokenStats{
+					Limit:     defaultRateLimit,
+					Remaining: defaultRateLimit,
+				},
+			}
+		}
+		delete(kept, token)
+		budget.stats.Index = len(s.tokens)
+		s.tokens = append(s.tokens, budget)
+	}
+}
+
+// pick returns the token with the most remaining budget, tokens in skip are ignored.
+// If all the other tokens are exhausted, it returns nil and the duration until the earliest reset.
+// If there is no token could be used at all, it returns nil and zero.
+func (s *scheduler) pick(skip map[*tokenBudget]bool) (*tokenBudget, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var best *tokenBudget
+	var earliestReset time.Time
+	for _, token := range s.tokens {
+		if skip[token] || token.stats.Quarantined {
+			continue
+		}
+		if token.exhausted(now) {
+			if earliestReset.IsZero() || token.stats.ResetAt.Before(earliestReset) {
+				earliestReset = token.stats.ResetAt
+			}
+			continue
+		}
+		if best == nil || token.remaining(now) > best.remaining(now) {
+			best = token
+		}
+	}
+
+	if best != nil {
+		return best, 0
+	}
+	if earliestReset.IsZero() {
+		return nil, 0
+	}
+	wait := earliestReset.Sub(now)
+	if wait <= 0 {
+		// The reset time is reached but not refreshed yet, wait a moment rather than spinning.
+		wait = time.Second
+	}
+	return nil, wait
+}
+
+// update records the rate limit returned with a successful query.
+func (s *scheduler) update(token *tokenBudget, rateLimit RateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rateLimit.ResetAt.IsZero() {
+		// github did not return the rate limit, count the query against a budget assumed to reset
+		// every rateLimitWindow, so the token is never exhausted without a time to reset.
+		token.stats.Queries++
+		if now := s.now(); !token.stats.ResetAt.After(now) {
+			token.stats.Remaining = token.stats.Limit
+			token.stats.ResetAt = now.Add(rateLimitWindow)
+		}
+		if token.stats.Remaining > 0 {
+			token.stats.Remaining--
+		}
+		return
+	}
+
+	token.stats.Queries++
+	token.stats.Cost += int(rateLimit.Cost)
+	token.lastCost = int(rateLimit.Cost)
+	if rateLimit.Limit > 0 {
+		token.stats.Limit = int(rateLimit.Limit)
+	}
+	token.stats.Remaining = int(rateLimit.Remaining)
+	token.stats.ResetAt = rateLimit.ResetAt.Time
+}
+
+// used records a successful query which did not fetch its rate limit.
+func (s *scheduler) used(token *tokenBudget) {
+	s.update(token, RateLimit{})
+}
+
+// fail records a failed query.
+func (s *scheduler) fail(token *tokenBudget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.stats.Errors++
+}
+
+// exhaust records that the token is rejected by the rate limit,
+// the token will not be used until its budget resets.
+func (s *scheduler) exhaust(token *tokenBudget, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.stats.Errors++
+	token.stats.Remaining = 0
+	if now := s.now(); !token.stats.ResetAt.After(now) {
+		token.stats.ResetAt = now.Add(retryAfter)
+	}
+}
+
+// quarantine records that the token is unauthorized, the token will not be used any more.
+func (s *scheduler) quarantine(token *tokenBudget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.stats.Errors++
+	token.stats.Quarantined = true
+}
+
+// stats returns a snapshot of the budget of all tokens.
+func (s *scheduler) stats() []TokenStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	stats := make([]TokenStats, len(s.tokens))
+	for i, token := range s.tokens {
+		stats[i] = token.stats
+		stats[i].Remaining = token.remaining(now)
+	}
+	return stats
+}
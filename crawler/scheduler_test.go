@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func newTestScheduler(size int, now time.Time) *scheduler {
+	tokens := make([]string, size)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token%d", i)
+	}
+	s := newScheduler()
+	s.now = func() time.Time { return now }
+	s.reset(tokens, func(string) *githubv4.Client { return nil })
+	return s
+}
+
+func TestSchedulerPickMostRemaining(t *testing.T) {
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestScheduler(3, now)
+	resetAt := githubv4.DateTime{Time: now.Add(time.Hour)}
+	s.update(s.tokens[0], RateLimit{Limit: 5000, Cost: 1, Remaining: 100, ResetAt: resetAt})
+	s.update(s.tokens[1], RateLimit{Limit: 5000, Cost: 1, Remaining: 3000, ResetAt: resetAt})
+	s.update(s.tokens[2], RateLimit{Limit: 5000, Cost: 1, Remaining: 2000, ResetAt: resetAt})
+
+	token, wait := s.pick(nil)
+	if token == nil || token.stats.Index != 1 || wait != 0 {
+		t.Errorf("pick returns %v, %v; expected token 1", token, wait)
+	}
+
+	token, _ = s.pick(map[*tokenBudget]bool{s.tokens[1]: true})
+	if token == nil || token.stats.Index != 2 {
+		t.Errorf("pick returns %v; expected token 2", token)
+	}
+}
+
+func TestSchedulerWaitUntilReset(t *testing.T) {
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestScheduler(2, now)
+	s.update(s.tokens[0], RateLimit{Limit: 5000, Cost: 1, Remaining: 0, ResetAt: githubv4.DateTime{Time: now.Add(30 * time.Minute)}})
+	s.update(s.tokens[1], RateLimit{Limit: 5000, Cost: 2, Remaining: 1, ResetAt: githubv4.DateTime{Time: now.Add(10 * time.Minute)}})
+
+	token, wait := s.pick(nil)
+	if token != nil || wait != 10*time.Minute {
+		t.Errorf("pick returns %v, %v; expected to wait 10m", token, wait)
+	}
+
+	// the budget comes back after reset.
+	s.now = func() time.Time { return now.Add(10 * time.Minute) }
+	token, _ = s.pick(nil)
+	if token == nil || token.stats.Index != 1 {
+		t.Errorf("pick returns %v; expected token 1", token)
+	}
+
+	stats := s.stats()
+	if stats[1].Remaining != 5000 || stats[1].Cost != 2 || stats[1].Queries != 1 {
+		t.Errorf("stats of token 1 is %+v", stats[1])
+	}
+
+	token, wait = s.pick(map[*tokenBudget]bool{s.tokens[0]: true, s.tokens[1]: true})
+	if token != nil || wait != 0 {
+		t.Errorf("pick returns %v, %v; expected no token", token, wait)
+	}
+}
+
+func TestSchedulerUpdateWithoutRateLimit(t *testing.T) {
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestScheduler(1, now)
+	s.update(s.tokens[0], RateLimit{Limit: 5000, Cost: 1, Remaining: 1, ResetAt: githubv4.DateTime{Time: now.Add(-time.Minute)}})
+
+	// the budget has reset since the last rate limit, which is not returned by the response.
+	s.update(s.tokens[0], RateLimit{})
+	token, wait := s.pick(nil)
+	if token == nil || wait != 0 {
+		t.Errorf("pick returns %v, %v; expected token 0", token, wait)
+	}
+	stats := s.stats()
+	if stats[0].Remaining != 4999 || !stats[0].ResetAt.Equal(now.Add(time.Hour)) || stats[0].Queries != 2 {
+		t.Errorf("stats of token 0 is %+v", stats[0])
+	}
+
+	// the token waits for the assumed reset instead of being exhausted forever.
+	s.tokens[0].stats.Remaining = 1
+	s.update(s.tokens[0], RateLimit{})
+	token, wait = s.pick(nil)
+	if token != nil || wait != time.Hour {
+		t.Errorf("pick returns %v, %v; expected to wait 1h", token, wait)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+
+	"github.com/google/martian/log"
+	"github.com/shurcooL/githubv4"
+)
+
+type searchIssueQuery struct {
+	Search struct {
+		IssueCount githubv4.Int
+		Nodes      []struct {
+			Issue `graphql:"... on Issue"`
+		}
+		PageInfo PageInfo
+	} `graphql:"search(query: $searchQuery, type: ISSUE, first: 100, after: $searchCursor)"`
+	RateLimit RateLimit
+}
+
+func (q searchIssueQuery) GetPageInfo() PageInfo {
+	return q.Search.PageInfo
+}
+
+func (q searchIssueQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q searchIssueQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q searchIssueQuery) GetCursorVariable() string {
+	return "searchCursor"
+}
+
+type searchIssueWithCommentsQuery struct {
+	Search struct {
+		IssueCount githubv4.Int
+		Nodes      []struct {
+			issueWithFirstComments `graphql:"... on Issue"`
+		}
+		PageInfo PageInfo
+	} `graphql:"search(query: $searchQuery, type: ISSUE, first: 100, after: $searchCursor)"`
+	RateLimit RateLimit
+}
+
+func (q searchIssueWithCommentsQuery) GetPageInfo() PageInfo {
+	return q.Search.PageInfo
+}
+
+func (q searchIssueWithCommentsQuery) GetQuery() Query {
+	return Query(q)
+}
+
+func (q searchIssueWithCommentsQuery) GetRateLimit() RateLimit {
+	return q.RateLimit
+}
+
+func (q searchIssueWithCommentsQuery) GetCursorVariable() string {
+	return "searchCursor"
+}
+
+// searchIssues fetch issues matched by the search query, with the first page of comments if withComments is true.
+// Pull requests matched are ignored.
+func searchIssues(ctx context.Context, client ClientV4, searchQuery string, withComments bool) ([]issueWithFirstComments, error) {
+	variables := map[string]interface{}{
+		"searchQuery":  githubv4.String(searchQuery),
+		"searchCursor": (*githubv4.String)(nil),
+	}
+
+	var issues []issueWithFirstComments
+	if withComments {
+		var query searchIssueWithCommentsQuery
+		queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
+		if err != nil {
+			log.Errorf(" search issue with comments error")
+			return nil, err
+		}
+		for _, query := range queryList {
+			for _, node := range query.(searchIssueWithCommentsQuery).Search.Nodes {
+				if node.Number != 0 {
+					issues = append(issues, node.issueWithFirstComments)
+				}
+			}
+		}
+	} else {
+		var query searchIssueQuery
+		queryList, err := FetchAllQueriesContext(ctx, client, &query, variables)
+		if err != nil {
+			log.Errorf(" search issue error")
+			return nil, err
+		}
+		for _, query := range queryList {
+			for _, node := range query.(searchIssueQuery).Search.Nodes {
+				if node.Number != 0 {
+					issues = append(issues, issueWithFirstComments{Issue: node.Issue})
+				}
+			}
+		}
+	}
+
+	if err := fetchRestOfIssuesWithFirstComments(ctx, client, "", "", issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// SearchIssueWithComments fetch issues matched by the search query combined with comments,
+// the query is in the syntax of github search, such as `repo:pingcap/tidb is:issue label:type/bug created:>2020-01-01`.
+// More info of the syntax could be found in https://docs.github.com/en/free-pro-team@latest/github/searching-for-information-on-github/searching-issues-and-pull-requests
+// Github returns at most 1000 results for a search, split the query by created time if there are more issues.
+func SearchIssueWithComments(ctx context.Context, client ClientV4, searchQuery string, opts FetchOptions) (*[]IssueWithComments, []error) {
+	issues, err := searchIssues(ctx, client, searchQuery, opts.BatchComments)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return combineComments(ctx, client, "", "", issues, opts)
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFetchIssueWithCommentsByFilter(t *testing.T) {
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "issues(") {
+			filterBy := variables["filterBy"].(map[string]interface{})
+			if _, ok := filterBy["labels"]; ok {
+				t.Errorf("labels should be omitted, filterBy = %v", filterBy)
+			}
+			if filterBy["milestone"] != "3" || filterBy["assignee"] != "someone" {
+				t.Errorf("filterBy = %v", filterBy)
+			}
+			return `{"data":{"repository":{"issues":{"nodes":[{"number":1}]}}}}`
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[{"body":"comment"}]}}}}}`
+	})
+	defer server.Close()
+
+	filter := IssueFilter{Milestone: "3", Assignee: "someone"}
+	issues, errs := FetchIssueWithComments(context.Background(), newTestPool(server.URL).Client(), "pingcap", "tidb", filter, FetchOptions{})
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if len(*issues) != 1 || len(*(*issues)[0].Comments) != 1 {
+		t.Errorf("issues = %v", *issues)
+	}
+}
+
+func TestSearchIssueWithComments(t *testing.T) {
+	server := newFakeGithubServer(t, func(query string, variables map[string]interface{}) string {
+		if strings.Contains(query, "search(") {
+			if variables["searchQuery"] != "is:issue label:type/bug" {
+				t.Errorf("searchQuery = %v", variables["searchQuery"])
+			}
+			return `{"data":{"search":{"issueCount":2,"nodes":[
+				{"number":1,"repository":{"name":"tikv","owner":{"login":"tikv"}}},
+				{}]}}}`
+		}
+		if variables["repositoryOwner"] != "tikv" || variables["repositoryName"] != "tikv" {
+			t.Errorf("comments are fetched from %v/%v", variables["repositoryOwner"], variables["repositoryName"])
+		}
+		return `{"data":{"repository":{"issue":{"comments":{"nodes":[{"body":"comment"}]}}}}}`
+	})
+	defer server.Close()
+
+	issues, errs := SearchIssueWithComments(context.Background(), newTestPool(server.URL).Client(), "is:issue label:type/bug", FetchOptions{})
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if len(*issues) != 1 || (*issues)[0].Repository.Name != "tikv" || len(*(*issues)[0].Comments) != 1 {
+		t.Errorf("issues = %v", *issues)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// Actor define the actor of a timeline event
+type Actor struct {
+	Login githubv4.String
+}
+
+// TimelineItem define an item in the timeline of an issue, only the event field named by Typename is filled.
+// More info of timeline items could be found in https://docs.github.com/en/free-pro-team@latest/graphql/reference/unions#issuetimelineitems
+type TimelineItem struct {
+	Typename             string `graphql:"__typename"`
+	CrossReferencedEvent struct {
+		Actor     Actor
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on CrossReferencedEvent"`
+	AssignedEvent struct {
+		Actor    Actor
+		Assignee struct {
+			User struct {
+				Login githubv4.String
+				Email githubv4.String
+			} `graphql:"... on User"`
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on AssignedEvent"`
+	UnassignedEvent struct {
+		Actor    Actor
+		Assignee struct {
+			User struct {
+				Login githubv4.String
+				Email githubv4.String
+			} `graphql:"... on User"`
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on UnassignedEvent"`
+	LabeledEvent struct {
+		Actor Actor
+		Label struct {
+			Name githubv4.String
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on LabeledEvent"`
+	UnlabeledEvent struct {
+		Actor Actor
+		Label struct {
+			Name githubv4.String
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on UnlabeledEvent"`
+	ClosedEvent struct {
+		Actor Actor
+		// Closer is the commit or pull request which closes the issue, it is empty if the issue is closed manually.
+		Closer struct {
+			Typename string `graphql:"__typename"`
+			Commit   struct {
+				Oid githubv4.GitObjectID
+			} `graphql:"... on Commit"`
+			PullRequest struct {
+				Number githubv4.Int
+			} `graphql:"... on PullRequest"`
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on ClosedEvent"`
+	ReopenedEvent struct {
+		Actor     Actor
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on ReopenedEvent"`
+	MilestonedEvent struct {
+		Actor          Actor
+		MilestoneTitle githubv4.String
+		CreatedAt      githubv4.DateTime
+	} `graphql:"... on MilestonedEvent"`
+	DemilestonedEvent struct {
+		Actor          Actor
+		MilestoneTitle githubv4.String
+		CreatedAt      githubv4.DateTime
+	} `graphql:"... on DemilestonedEvent"`
+	ConnectedEvent struct {
+		Actor  Actor
+		Source struct {
+			Typename string `graphql:"__typename"`
+			Issue    struct {
+				Number githubv4.Int
+			} `graphql:"... on Issue"`
+			PullRequest struct {
+				Number githubv4.Int
+			} `graphql:"... on PullRequest"`
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on ConnectedEvent"`
+	TransferredEvent struct {
+		Actor          Actor
+		FromRepository struct {
+			NameWithOwner githubv4.String
+		}
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on TransferredEvent"`
+}
+
+// The typenames of timeline items decoded in TimelineItem
+const (
+	CrossReferencedEventType = "CrossReferencedEvent"
+	AssignedEventType        = "AssignedEvent"
+	UnassignedEventType      = "UnassignedEvent"
+	LabeledEventType         = "LabeledEvent"
+	UnlabeledEventType       = "UnlabeledEvent"
+	ClosedEventType          = "ClosedEvent"
+	ReopenedEventType        = "ReopenedEvent"
+	MilestonedEventType      = "MilestonedEvent"
+	DemilestonedEventType    = "DemilestonedEvent"
+	ConnectedEventType       = "ConnectedEvent"
+	TransferredEventType     = "TransferredEvent"
+)
+
+// CreatedAt returns the time the item is created, it is zero for the types not decoded.
+func (item *TimelineItem) CreatedAt() time.Time {
+	switch item.Typename {
+	case CrossReferencedEventType:
+		return item.CrossReferencedEvent.CreatedAt.Time
+	case AssignedEventType:
+		return item.AssignedEvent.CreatedAt.Time
+	case UnassignedEventType:
+		return item.UnassignedEvent.CreatedAt.Time
+	case LabeledEventType:
+		return item.LabeledEvent.CreatedAt.Time
+	case UnlabeledEventType:
+		return item.UnlabeledEvent.CreatedAt.Time
+	case ClosedEventType:
+		return item.ClosedEvent.CreatedAt.Time
+	case ReopenedEventType:
+		return item.ReopenedEvent.CreatedAt.Time
+	case MilestonedEventType:
+		return item.MilestonedEvent.CreatedAt.Time
+	case DemilestonedEventType:
+		return item.DemilestonedEvent.CreatedAt.Time
+	case ConnectedEventType:
+		return item.ConnectedEvent.CreatedAt.Time
+	case TransferredEventType:
+		return item.TransferredEvent.CreatedAt.Time
+	}
+	return time.Time{}
+}
+
+// LabelsAt returns the names of labels the issue had at t, sorted by name.
+// It undoes the labeled and unlabeled events after t on the current labels,
+// so the labels and timeline items of the issue must be fetched completely.
+// It returns nil if the issue is not created at t.
+func (issue *Issue) LabelsAt(t time.Time) []string {
+	if t.Before(issue.CreatedAt.Time) {
+		return nil
+	}
+
+	labels := make(map[string]bool, len(issue.Labels.Nodes))
+	for _, label := range issue.Labels.Nodes {
+		labels[string(label.Name)] = true
+	}
+
+	// the timeline is in chronological order, undo the latest event first.
+	for i := len(issue.TimelineItems.Nodes) - 1; i >= 0; i-- {
+		item := issue.TimelineItems.Nodes[i]
+		if !item.CreatedAt().After(t) {
+			continue
+		}
+		switch item.Typename {
+		case LabeledEventType:
+			delete(labels, string(item.LabeledEvent.Label.Name))
+		case UnlabeledEventType:
+			labels[string(item.UnlabeledEvent.Label.Name)] = true
+		}
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+func labelEvent(typename, label string, t time.Time) TimelineItem {
+	item := TimelineItem{Typename: typename}
+	if typename == LabeledEventType {
+		item.LabeledEvent.Label.Name = githubv4.String(label)
+		item.LabeledEvent.CreatedAt = githubv4.DateTime{Time: t}
+	} else {
+		item.UnlabeledEvent.Label.Name = githubv4.String(label)
+		item.UnlabeledEvent.CreatedAt = githubv4.DateTime{Time: t}
+	}
+	return item
+}
+
+func TestIssueLabelsAt(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	var issue Issue
+	issue.CreatedAt = githubv4.DateTime{Time: day(1)}
+	issue.Labels.Nodes = make([]struct{ Name githubv4.String }, 2)
+	issue.Labels.Nodes[0].Name = "type/bug"
+	issue.Labels.Nodes[1].Name = "severity/minor"
+	issue.TimelineItems.Nodes = []TimelineItem{
+		labelEvent(LabeledEventType, "type/bug", day(1)),
+		labelEvent(LabeledEventType, "severity/critical", day(2)),
+		labelEvent(UnlabeledEventType, "severity/critical", day(5)),
+		labelEvent(LabeledEventType, "severity/minor", day(5)),
+	}
+
+	cases := []struct {
+		t      time.Time
+		labels []string
+	}{
+		{day(1).Add(-time.Hour), nil},
+		{day(1), []string{"type/bug"}},
+		{day(3), []string{"severity/critical", "type/bug"}},
+		{day(6), []string{"severity/minor", "type/bug"}},
+	}
+	for _, c := range cases {
+		if labels := issue.LabelsAt(c.t); !reflect.DeepEqual(labels, c.labels) {
+			t.Errorf("labels at %v are %v; expected %v", c.t, labels, c.labels)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "crypto/sha1"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Dimension define how issues are grouped in a breakdown
+type Dimension string
+
+// Dimensions of breakdowns
+const (
+    // DimensionRepo groups issues by their repository
+    DimensionRepo Dimension = "repo"
+    // DimensionSIG groups issues by their sig/* labels
+    DimensionSIG Dimension = "sig"
+    // DimensionSeverity groups issues by their severities weighted by the WeightModel
+    DimensionSeverity Dimension = "severity"
+    // DimensionComponent groups issues by their component/* labels
+    DimensionComponent Dimension = "component"
+    // DimensionAssignee groups issues by their assignees
+    DimensionAssignee Dimension = "assignee"
+)
+
+// Breakdown define DI of the issues in a group.
+// An issue with several values of a dimension, e.g. two sig labels, is in the group of each value,
+// so the DI of groups may add up to more than the DI of all issues.
+type Breakdown struct {
+    // Group is the value of each dimension of the issues, which is "" for issues without the dimension
+    Group map[Dimension]string
+    // DI is DI at the end time
+    DI float64
+    // CreatedDI, ClosedDI and ReopenedDI are DI of issues created, closed and reopened in [start time, end time),
+    // issues closed or reopened several times are counted each time
+    CreatedDI  float64
+    ClosedDI   float64
+    ReopenedDI float64
+}
+
+// checkDimensions returns an error if dimensions are unsupported or duplicated
+func checkDimensions(dimensions []Dimension) error {
+    if len(dimensions) == 0 {
+        return errors.New("no dimension")
+    }
+    seen := make(map[Dimension]bool)
+    for _, dimension := range dimensions {
+        switch dimension {
+        case DimensionRepo, DimensionSIG, DimensionSeverity, DimensionComponent, DimensionAssignee:
+        default:
+            return fmt.Errorf("unsupported dimension %q", dimension)
+        }
+        if seen[dimension] {
+            return fmt.Errorf("duplicated dimension %q", dimension)
+        }
+        seen[dimension] = true
+    }
+    return nil
+}
+
+// values returns the values of issue in dimension, severities are the ones weighted
+func values(issue Issue, dimension Dimension, severities []string) []string {
+    var values []string
+    switch dimension {
+    case DimensionRepo:
+        values = []string{issue.RepoName}
+    case DimensionSeverity:
+        values = severities
+    case DimensionAssignee:
+        values = issue.Assignees
+    default:
+        values = issue.Label[string(dimension)]
+    }
+    if len(values) == 0 {
+        return []string{""}
+    }
+    return values
+}
+
+// breakdown returns DI at endTime and created, closed and reopened DI in [startTime, endTime) of each group of issues
+// by dimensions in a single scan of the snapshot, with the labels of issues at endTime,
+// and the report of the issues counted in the groups and skipped from all of them
+func (s *snapshot) breakdown(dimensions []Dimension, startTime, endTime time.Time, model *WeightModel) ([]Breakdown, *Report) {
+    groups := make(map[string]*Breakdown)
+    report := newReport()
+    for _, issue := range s.issues {
+        open := openAt(issue, endTime)
+        created := !issue.CreatedAt.Before(startTime) && issue.CreatedAt.Before(endTime)
+        closed, reopened := 0, 0
+        for i, interval := range issue.intervals() {
+            if !interval.End.IsZero() && !interval.End.Before(startTime) && interval.End.Before(endTime) {
+                closed++
+            }
+            if i > 0 && !interval.Start.Before(startTime) && interval.Start.Before(endTime) {
+                reopened++
+            }
+        }
+        if !open && !created && closed == 0 && reopened == 0 {
+            continue
+        }
+        issue, ok := s.at(issue, endTime)
+        if !ok {
+            continue
+        }
+        di, severities, err := model.weigh(issue.Label)
+        if err != nil {
+            report.skip(skipReason(err), issue.Number)
+            continue
+        }
+        report.count(severities)
+
+        // the cartesian product of the values of each dimension.
+        combinations := [][]string{{}}
+        for _, dimension := range dimensions {
+            next := make([][]string, 0, len(combinations))
+            for _, combination := range combinations {
+                for _, value := range values(issue, dimension, severities) {
+                    next = append(next, append(combination[:len(combination):len(combination)], value))
+                }
+            }
+            combinations = next
+        }
+
+        for _, combination := range combinations {
+            key := strings.Join(combination, "\x00")
+            group, ok := groups[key]
+            if !ok {
+                group = &Breakdown{Group: make(map[Dimension]string, len(dimensions))}
+                for i, dimension := range dimensions {
+                    group.Group[dimension] = combination[i]
+                }
+                groups[key] = group
+            }
+
+            // issues with several severities are weighted by each severity in the severity groups.
+            weight := di
+            if severity := group.Group[DimensionSeverity]; severity != "" {
+                weight = model.Weights[severity]
+            }
+            if open {
+                group.DI += weight
+            }
+            if created {
+                group.CreatedDI += weight
+            }
+            group.ClosedDI += weight * float64(closed)
+            group.ReopenedDI += weight * float64(reopened)
+        }
+    }
+
+    keys := make([]string, 0, len(groups))
+    for key := range groups {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    breakdowns := make([]Breakdown, 0, len(keys))
+    for _, key := range keys {
+        breakdowns = append(breakdowns, *groups[key])
+    }
+    return breakdowns, report
+}
+
+// ComputeBreakdown returns DI at endTime and created, closed and reopened DI in [startTime, endTime) of each group of issues
+// by dimensions with the report of the issues, the issues are loaded from issueDB once for all groups
+// only non-empty repo and sig will be involved, unless there is a filter of WithFilter
+func ComputeBreakdown(issueDB *sql.DB, repo, sig string, dimensions []Dimension, startTime, endTime time.Time,
+    opts ...Option) ([]Breakdown, *Report, error) {
+    return NewEngine(NewSQLSource(issueDB), opts...).Breakdown(repo, sig, dimensions, startTime, endTime)
+}
+
+// ProcessBreakdown computes the breakdown of DI by dimensions and stores it into table DI_BREAKDOWN,
+// or writes it to the sink of WithSink
+func ProcessBreakdown(issueDB, diDB *sql.DB, repo, sig string, dimensions []Dimension, startTime, endTime time.Time,
+    opts ...Option) error {
+    o := newOptions(opts)
+    breakdowns, _, err := ComputeBreakdown(issueDB, repo, sig, dimensions, startTime, endTime, opts...)
+    if err != nil {
+        return err
+    }
+
+    err = o.sinkOf(diDB).WriteBreakdown(repo, sig, dimensions, startTime, endTime, breakdowns)
+
+    return err
+}
+
+// breakdownColumns are the columns of the value of each dimension in table DI_BREAKDOWN
+var breakdownColumns = []Dimension{DimensionRepo, DimensionSIG, DimensionSeverity, DimensionComponent, DimensionAssignee}
+
+// groupHash returns the hash identifying a group of a breakdown in table DI_BREAKDOWN
+func groupHash(repo, sig, dimensions string, group map[Dimension]string) string {
+    parts := []string{repo, sig, dimensions}
+    for _, column := range breakdownColumns {
+        parts = append(parts, group[column])
+    }
+    hash := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+    return hex.EncodeToString(hash[:])
+}
+
+// storeBreakdown upserts breakdowns into table DI_BREAKDOWN and commits,
+// the stored breakdowns by the same dimensions overlapping [startTime, endTime) are deleted first if replaceRange is true
+func storeBreakdown(db *sql.DB, repo, sig string, dimensions []Dimension, startTime, endTime time.Time,
+    breakdowns []Breakdown, replaceRange bool) (err error) {
+    names := make([]string, len(dimensions))
+    for i, dimension := range dimensions {
+        names[i] = string(dimension)
+    }
+    joined := strings.Join(names, ",")
+
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer func(){
+        if err != nil {
+            // the transaction is done if it fails to commit.
+            if err1 := tx.Rollback(); err1 != nil && err1 != sql.ErrTxDone {
+                err = fmt.Errorf("%w, and rollback failed: %v", err, err1)
+            }
+        }
+    }()
+    if replaceRange {
+        _, err = tx.Exec(`DELETE FROM DI_BREAKDOWN WHERE REPO = ? AND SIG = ? AND DIMENSIONS = ? AND START_TIME < ? AND END_TIME > ?`,
+            repo, sig, joined, endTime, startTime)
+        if err != nil {
+            return err
+        }
+    }
+    for _, breakdown := range breakdowns {
+        _, err = tx.Exec(`INSERT INTO DI_BREAKDOWN(GROUP_HASH, REPO, SIG, DIMENSIONS, REPO_VALUE, SIG_VALUE, SEVERITY_VALUE,
+                            COMPONENT_VALUE, ASSIGNEE_VALUE, START_TIME, END_TIME, DI, CREATED_DI, CLOSED_DI, REOPENED_DI)
+                            VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+                            ON DUPLICATE KEY UPDATE DI = VALUES(DI), CREATED_DI = VALUES(CREATED_DI), CLOSED_DI = VALUES(CLOSED_DI),
+                                REOPENED_DI = VALUES(REOPENED_DI)`,
+            groupHash(repo, sig, joined, breakdown.Group), repo, sig, joined,
+            breakdown.Group[DimensionRepo], breakdown.Group[DimensionSIG], breakdown.Group[DimensionSeverity],
+            breakdown.Group[DimensionComponent], breakdown.Group[DimensionAssignee],
+            startTime, endTime, breakdown.DI, breakdown.CreatedDI, breakdown.ClosedDI, breakdown.ReopenedDI)
+        if err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+func TestSnapshotBreakdown(t *testing.T) {
+    s := newTestSnapshot()
+    s.issues[0].RepoName, s.issues[1].RepoName, s.issues[2].RepoName = "tidb", "tidb", "tikv"
+    addLabel(&s.issues[0], "sig/execution")
+    addLabel(&s.issues[1], "sig/execution")
+    addLabel(&s.issues[1], "sig/planner")
+    s.issues[1].Assignees = []string{"a", "b"}
+
+    startTime := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+    endTime := time.Date(2020, 9, 9, 0, 0, 0, 0, time.UTC)
+    breakdowns, report := s.breakdown([]Dimension{DimensionRepo, DimensionSIG}, startTime, endTime, &DefaultWeightModel)
+    must(t, len(breakdowns), 3, "len(breakdowns)")
+    must(t, breakdowns[0].Group[DimensionRepo], "tidb", "breakdowns[0].Group[repo]")
+    must(t, breakdowns[0].Group[DimensionSIG], "execution", "breakdowns[0].Group[sig]")
+    must(t, breakdowns[0].DI, criticalDI+majorDI, "breakdowns[0].DI")
+    must(t, breakdowns[1].Group[DimensionSIG], "planner", "breakdowns[1].Group[sig]")
+    must(t, breakdowns[1].CreatedDI, majorDI, "breakdowns[1].CreatedDI")
+    must(t, breakdowns[2].Group[DimensionRepo], "tikv", "breakdowns[2].Group[repo]")
+    must(t, breakdowns[2].Group[DimensionSIG], "", "breakdowns[2].Group[sig]")
+    must(t, breakdowns[2].DI, minorDI, "breakdowns[2].DI")
+
+    must(t, report.CountedTotal(), 3, "report.CountedTotal()")
+
+    // issues without a severity are skipped from every group.
+    s.issues = append(s.issues, Issue{Number: 4, CreatedAt: startTime, Label: map[string][]string{"type": {"bug"}}})
+    breakdowns, report = s.breakdown([]Dimension{DimensionAssignee, DimensionSeverity}, startTime, endTime, &DefaultWeightModel)
+    must(t, fmt.Sprint(report.Skipped[SkipNoSeverity]), "[4]", "report.Skipped[SkipNoSeverity]")
+    must(t, len(breakdowns), 4, "len(breakdowns)")
+    must(t, breakdowns[0].Group[DimensionAssignee], "", "breakdowns[0].Group[assignee]")
+    must(t, breakdowns[0].Group[DimensionSeverity], "critical", "breakdowns[0].Group[severity]")
+    must(t, breakdowns[3].Group[DimensionAssignee], "b", "breakdowns[3].Group[assignee]")
+    must(t, breakdowns[3].DI, majorDI, "breakdowns[3].DI")
+
+    must(t, checkDimensions([]Dimension{DimensionRepo, DimensionRepo}) != nil, true, "duplicated dimensions are rejected")
+    must(t, checkDimensions([]Dimension{"milestone"}) != nil, true, "unsupported dimensions are rejected")
+}
@@ -14,12 +14,10 @@
 package di
 
 import (
-    "database/sql"
-    "log"
     "time"
 )
 
-// DI constants
+// DI constants of DefaultWeightModel
 const (
     criticalDI = 10.0
     majorDI    = 3.0
@@ -35,11 +33,16 @@ type Issue struct {
     ID           uint
     Number       int
     RepositoryID int
+    RepoName     string
     Closed       bool
     ClosedAt     time.Time
     CreatedAt    time.Time
     Title        string
     Label        map[string][]string
+    LabelNames   []string
+    LabelEvents  []LabelEvent
+    Intervals    []Interval
+    Assignees    []string
 }
 
 // Interval DI struct
@@ -55,51 +58,19 @@ type InstantDI struct {
     Value float64
 }
 
-// calculateDI returns total DI of specified issues
-func calculateDI(issues []Issue) float64 {
+// calculateDI returns total DI of specified issues weighted by model,
+// and the report of issues counted and skipped
+func calculateDI(issues []Issue, model *WeightModel) (float64, *Report) {
     di := 0.0
+    report := newReport()
     for _, issue := range issues {
-        severity, ok := issue.Label["severity"]
-        if !ok {
-            log.Printf("Issue %v has no severity", issue.Number)
-            continue
-        }
-
-        if len(severity) > 1 {
-            log.Printf("Issue %v has multiple severities", issue.Number)
+        weight, severities, err := model.weigh(issue.Label)
+        if err != nil {
+            report.skip(skipReason(err), issue.Number)
             continue
         }
-
-        switch severity[0] {
-        case "critical":
-            di += criticalDI
-        case "major":
-            di += majorDI
-        case "moderate":
-            di += moderateDI
-        case "minor":
-            di += minorDI
-        default:
-            log.Printf("Issue %v has unsupported severity %s", issue.Number, severity)
-        }
+        di += weight
+        report.count(severities)
     }
-    return di
+    return di, report
 }
-
-// parseIssues returns issues parsed from sql.Rows
-func parseIssues(rows *sql.Rows) ([]Issue, error) {
-    issues := make([]Issue, 0)
-
-    for rows.Next() {
-        var issue Issue
-
-        err := rows.Scan(&issue.ID, &issue.Number)
-        if err != nil {
-            return nil, err
-        }
-
-        issues = append(issues, issue)
-    }
-
-    return issues, nil
-}
\ No newline at end of file
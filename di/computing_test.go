@@ -15,6 +15,8 @@ package di
 
 import (
     "database/sql"
+    "errors"
+    "fmt"
     "log"
     "os"
     "testing"
@@ -35,13 +37,6 @@ func init() {
     issueDB.SetConnMaxLifetime(10 * time.Minute)
 }
 
-func TestGetLabels(t *testing.T) {
-    issue := Issue{ID: 1}
-    labels, err := getLabels(issueDB, issue)
-    must(t, err, nil, "err")
-    must(t, len(labels), 1, "len(labels)")
-}
-
 func TestCalculateDi(t *testing.T) {
     minorIssue := Issue{Label: map[string][]string{"severity": {"minor"}}}
     moderateIssue := Issue{Label: map[string][]string{"severity": {"moderate"}}}
@@ -49,13 +44,69 @@ func TestCalculateDi(t *testing.T) {
     criticalIssue := Issue{Label: map[string][]string{"severity": {"critical"}}}
     badIssue := Issue{Label: map[string][]string{"severity": {"unknown"}}}
 
-    di := calculateDI([]Issue{minorIssue, moderateIssue, majorIssue, criticalIssue})
+    di, _ := calculateDI([]Issue{minorIssue, moderateIssue, majorIssue, criticalIssue}, &DefaultWeightModel)
     must(t, di, minorDI+moderateDI+majorDI+criticalDI, "di")
 
-    di = calculateDI([]Issue{badIssue, criticalIssue})
+    di, _ = calculateDI([]Issue{badIssue, criticalIssue}, &DefaultWeightModel)
     must(t, di, criticalDI, "di")
 
-    di = calculateDI([]Issue{badIssue})
+    di, _ = calculateDI([]Issue{badIssue}, &DefaultWeightModel)
     must(t, di, 0.0, "di")
 
 }
+
+func TestCalculateDiReport(t *testing.T) {
+    issues := []Issue{
+        {Number: 1, Label: map[string][]string{"severity": {"major"}}},
+        {Number: 2, Label: map[string][]string{"severity": {"major"}}},
+        {Number: 5, Label: map[string][]string{"severity": {"unknown"}}},
+        {Number: 4, Label: map[string][]string{"severity": {"minor", "major"}}},
+        {Number: 3, Label: map[string][]string{"type": {"bug"}}},
+        {Number: 6, Label: map[string][]string{}},
+    }
+    di, report := calculateDI(issues, &DefaultWeightModel)
+    must(t, di, 2*majorDI, "di")
+    must(t, report.Counted["major"], 2, "report.Counted[major]")
+    must(t, report.CountedTotal(), 2, "report.CountedTotal()")
+    must(t, report.SkippedTotal(), 4, "report.SkippedTotal()")
+    must(t, fmt.Sprint(report.Skipped[SkipNoSeverity]), "[3 6]", "report.Skipped[SkipNoSeverity]")
+    must(t, fmt.Sprint(report.Skipped[SkipMultipleSeverities]), "[4]", "report.Skipped[SkipMultipleSeverities]")
+    must(t, fmt.Sprint(report.Skipped[SkipUnknownSeverity]), "[5]", "report.Skipped[SkipUnknownSeverity]")
+
+    report.Merge(report)
+    must(t, report.Counted["major"], 4, "report.Counted[major]")
+    must(t, report.SkippedTotal(), 4, "report.SkippedTotal()")
+}
+
+func TestWeightModel(t *testing.T) {
+    fallback := 0.5
+    model := WeightModel{
+        Weights:  map[string]float64{"P0": 10, "P1": 3, "P2": 1},
+        Fallback: &fallback,
+        Multiple: MaxMultiple,
+    }
+
+    di, err := model.Weight(map[string][]string{"P1": {""}, "type": {"bug"}})
+    must(t, err, nil, "err")
+    must(t, di, 3.0, "di")
+
+    di, err = model.Weight(map[string][]string{"P1": {""}, "P0": {""}})
+    must(t, err, nil, "err")
+    must(t, di, 10.0, "di")
+
+    di, err = model.Weight(map[string][]string{"type": {"bug"}})
+    must(t, err, nil, "err")
+    must(t, di, 0.5, "di")
+
+    model.Multiple = SumMultiple
+    di, err = model.Weight(map[string][]string{"P1": {""}, "P2": {""}})
+    must(t, err, nil, "err")
+    must(t, di, 4.0, "di")
+
+    _, err = DefaultWeightModel.Weight(map[string][]string{"severity": {"major", "minor"}})
+    must(t, errors.Is(err, ErrMultipleSeverities), true, "errors.Is(err, ErrMultipleSeverities)")
+    _, err = DefaultWeightModel.Weight(map[string][]string{"type": {"bug"}})
+    must(t, errors.Is(err, ErrNoSeverity), true, "errors.Is(err, ErrNoSeverity)")
+    _, err = DefaultWeightModel.Weight(map[string][]string{"severity": {"unknown"}})
+    must(t, errors.Is(err, ErrUnknownSeverity), true, "errors.Is(err, ErrUnknownSeverity)")
+}
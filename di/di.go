@@ -18,117 +18,118 @@ import (
     "time"
 )
 
-func ProcessCreatedDI(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time) error {
-    di, err := getCreatedDI(issueDB, repo, sig, startTime, endTime)
+func ProcessCreatedDI(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, opts ...Option) error {
+    o := newOptions(opts)
+    di, report, err := NewEngine(NewSQLSource(issueDB), opts...).CreatedDI(repo, sig, startTime, endTime)
     if err != nil {
         return err
     }
-    err = storeIntervalDI(diDB, "CREATED_DI", repo, sig, []IntervalDI{{StartTime: startTime, EndTime: endTime, Value: di}})
+    o.report("CREATED_DI", startTime, endTime, report)
+    err = o.sinkOf(diDB).WriteIntervalDI("CREATED_DI", repo, sig, []IntervalDI{{StartTime: startTime, EndTime: endTime, Value: di}})
     return err
 }
 
-func ProcessClosedDI(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time) error {
-    di, err := getClosedDI(issueDB, repo, sig, startTime, endTime)
+func ProcessClosedDI(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, opts ...Option) error {
+    o := newOptions(opts)
+    di, report, err := NewEngine(NewSQLSource(issueDB), opts...).ClosedDI(repo, sig, startTime, endTime)
     if err != nil {
         return err
     }
-    err = storeIntervalDI(diDB, "CLOSED_DI", repo, sig, []IntervalDI{{StartTime: startTime, EndTime: endTime, Value: di}})
+    o.report("CLOSED_DI", startTime, endTime, report)
+    err = o.sinkOf(diDB).WriteIntervalDI("CLOSED_DI", repo, sig, []IntervalDI{{StartTime: startTime, EndTime: endTime, Value: di}})
     return err
 }
 
-func ProcessDI(issueDB, diDB *sql.DB, repo, sig string, time time.Time) error {
-    di, err := getDI(issueDB, repo, sig, time)
+// ProcessReopenedDI stores DI of issues reopened in [startTime, endTime) into the sink of WithSink, table REOPENED_DI by default,
+// the reopen events are read from table ISSUE_EVENT, see SQLSource for the issues without them
+func ProcessReopenedDI(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, opts ...Option) error {
+    o := newOptions(opts)
+    di, report, err := NewEngine(NewSQLSource(issueDB), opts...).ReopenedDI(repo, sig, startTime, endTime)
     if err != nil {
         return err
     }
-    err = storeInstantDI(diDB, "DI", repo, sig, []InstantDI{{Time: time, Value: di}})
+    o.report("REOPENED_DI", startTime, endTime, report)
+    err = o.sinkOf(diDB).WriteIntervalDI("REOPENED_DI", repo, sig, []IntervalDI{{StartTime: startTime, EndTime: endTime, Value: di}})
     return err
 }
 
-func ProcessCreatedDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration) error {
-    dis := make([]IntervalDI, 0)
-
-    for startTime.Before(endTime) {
-        endTime := startTime.Add(frequency)
-
-        di, err := getCreatedDI(issueDB, repo, sig, startTime, endTime)
-        if err != nil {
-            return err
-        }
-
-        dis = append(dis, IntervalDI{
-            StartTime: startTime,
-            EndTime:   endTime,
-            Value:     di,
-        })
-
-        startTime = endTime
+func ProcessDI(issueDB, diDB *sql.DB, repo, sig string, time time.Time, opts ...Option) error {
+    o := newOptions(opts)
+    di, report, err := NewEngine(NewSQLSource(issueDB), opts...).DI(repo, sig, time)
+    if err != nil {
+        return err
     }
-
-    err := storeIntervalDI(diDB, "CREATED_DI", repo, sig, dis)
-
+    o.report("DI", time, time, report)
+    err = o.sinkOf(diDB).WriteInstantDI("DI", repo, sig, []InstantDI{{Time: time, Value: di}})
     return err
 }
 
-func ProcessClosedDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration) error {
-    dis := make([]IntervalDI, 0)
-
-    for startTime.Before(endTime) {
-        endTime := startTime.Add(frequency)
-
-        di, err := getClosedDI(issueDB, repo, sig, startTime, endTime)
-        if err != nil {
-            return err
-        }
-
-        dis = append(dis, IntervalDI{
-            StartTime: startTime,
-            EndTime:   endTime,
-            Value:     di,
-        })
-
-        startTime = endTime
+func ProcessCreatedDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration, opts ...Option) error {
+    o := newOptions(opts)
+    // only the reports of the stored metric are passed to the ReportFunc.
+    series, err := ComputeWindowSeries(issueDB, repo, sig, startTime, endTime, []WindowSpec{o.windowsOf(frequency)},
+        append(opts[:len(opts):len(opts)], WithReport(nil))...)
+    if err != nil {
+        return err
+    }
+    for i, di := range series[0].Created {
+        o.report("CREATED_DI", di.StartTime, di.EndTime, series[0].CreatedReports[i])
     }
 
-    err := storeIntervalDI(diDB, "CLOSED_DI", repo, sig, dis)
+    err = o.sinkOf(diDB).WriteIntervalDI("CREATED_DI", repo, sig, series[0].Created)
 
     return err
 }
 
-func ProcessDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration) error {
-    dis := make([]InstantDI, 0)
-    insDI, err := getDI(issueDB, repo, sig, startTime)
+func ProcessClosedDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration, opts ...Option) error {
+    o := newOptions(opts)
+    // only the reports of the stored metric are passed to the ReportFunc.
+    series, err := ComputeWindowSeries(issueDB, repo, sig, startTime, endTime, []WindowSpec{o.windowsOf(frequency)},
+        append(opts[:len(opts):len(opts)], WithReport(nil))...)
     if err != nil {
         return err
     }
-    dis = append(dis, InstantDI{
-        Time:  startTime,
-        Value: insDI,
-    })
+    for i, di := range series[0].Closed {
+        o.report("CLOSED_DI", di.StartTime, di.EndTime, series[0].ClosedReports[i])
+    }
 
-    for startTime.Before(endTime) {
+    err = o.sinkOf(diDB).WriteIntervalDI("CLOSED_DI", repo, sig, series[0].Closed)
 
-        createdDI, err := getCreatedDI(issueDB, repo, sig, startTime, startTime.Add(frequency))
-        if err != nil {
-            return err
-        }
+    return err
+}
 
-        closedDI, err := getClosedDI(issueDB, repo, sig, startTime, startTime.Add(frequency))
-        if err != nil {
-            return err
-        }
+// ProcessReopenedDIs stores DI of issues reopened in each window from startTime until endTime into the sink of WithSink, table REOPENED_DI by default,
+// the reopen events are read from table ISSUE_EVENT, see SQLSource for the issues without them
+func ProcessReopenedDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration, opts ...Option) error {
+    o := newOptions(opts)
+    // only the reports of the stored metric are passed to the ReportFunc.
+    series, err := ComputeWindowSeries(issueDB, repo, sig, startTime, endTime, []WindowSpec{o.windowsOf(frequency)},
+        append(opts[:len(opts):len(opts)], WithReport(nil))...)
+    if err != nil {
+        return err
+    }
+    for i, di := range series[0].Reopened {
+        o.report("REOPENED_DI", di.StartTime, di.EndTime, series[0].ReopenedReports[i])
+    }
 
-        insDI += createdDI - closedDI
+    err = o.sinkOf(diDB).WriteIntervalDI("REOPENED_DI", repo, sig, series[0].Reopened)
 
-        dis = append(dis, InstantDI{
-            Time:  startTime.Add(frequency),
-            Value: insDI,
-        })
+    return err
+}
 
-        startTime = startTime.Add(frequency)
+func ProcessDIs(issueDB, diDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequency time.Duration, opts ...Option) error {
+    o := newOptions(opts)
+    // only the reports of the stored metric are passed to the ReportFunc.
+    series, err := ComputeWindowSeries(issueDB, repo, sig, startTime, endTime, []WindowSpec{o.windowsOf(frequency)},
+        append(opts[:len(opts):len(opts)], WithReport(nil))...)
+    if err != nil {
+        return err
+    }
+    for i, di := range series[0].Instant {
+        o.report("DI", di.Time, di.Time, series[0].InstantReports[i])
     }
 
-    err = storeInstantDI(diDB, "DI", repo, sig, dis)
+    err = o.sinkOf(diDB).WriteInstantDI("DI", repo, sig, series[0].Instant)
 
     return err
 }
@@ -0,0 +1,160 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "errors"
+    "time"
+)
+
+// Engine calculates DI in memory of the issues loaded from an IssueSource,
+// it is configured by the same options as the Process functions
+type Engine struct {
+    source  IssueSource
+    options options
+}
+
+// NewEngine returns an Engine calculating DI of the issues of source
+func NewEngine(source IssueSource, opts ...Option) *Engine {
+    return &Engine{source: source, options: newOptions(opts)}
+}
+
+// snapshot loads the issues which may be counted in DI of [startTime, endTime], with their assignees if assignees is true,
+// only non-empty repo and sig will be involved, unless there is a filter of WithFilter
+func (e *Engine) snapshot(repo, sig string, startTime, endTime time.Time, assignees bool) (*snapshot, error) {
+    if e.source == nil {
+        return nil, errors.New("source is nil")
+    }
+    if startTime.After(endTime) {
+        return nil, errors.New("startTime > endTime")
+    }
+    filter := e.options.filterOf(repo, sig)
+    issues, err := e.source.Issues(filter, startTime, endTime, e.options.labelHistory, assignees)
+    if err != nil {
+        return nil, err
+    }
+    return &snapshot{issues: issues, history: e.options.labelHistory, filter: filter}, nil
+}
+
+// CreatedDI returns DI of issues created in [startTime, endTime)
+func (e *Engine) CreatedDI(repo, sig string, startTime, endTime time.Time) (float64, *Report, error) {
+    s, err := e.snapshot(repo, sig, startTime, endTime, false)
+    if err != nil {
+        return 0, nil, err
+    }
+    di, report := s.createdDI(startTime, endTime, e.options.weightModel)
+    return di, report, nil
+}
+
+// ClosedDI returns DI of issues closed in [startTime, endTime), counted each time they are closed
+func (e *Engine) ClosedDI(repo, sig string, startTime, endTime time.Time) (float64, *Report, error) {
+    s, err := e.snapshot(repo, sig, startTime, endTime, false)
+    if err != nil {
+        return 0, nil, err
+    }
+    di, report := s.closedDI(startTime, endTime, e.options.weightModel)
+    return di, report, nil
+}
+
+// ReopenedDI returns DI of issues reopened in [startTime, endTime), counted each time they are reopened
+func (e *Engine) ReopenedDI(repo, sig string, startTime, endTime time.Time) (float64, *Report, error) {
+    s, err := e.snapshot(repo, sig, startTime, endTime, false)
+    if err != nil {
+        return 0, nil, err
+    }
+    di, report := s.reopenedDI(startTime, endTime, e.options.weightModel)
+    return di, report, nil
+}
+
+// DI returns DI at a specified time, of issues in one of their open intervals at it
+func (e *Engine) DI(repo, sig string, time time.Time) (float64, *Report, error) {
+    s, err := e.snapshot(repo, sig, time, time, false)
+    if err != nil {
+        return 0, nil, err
+    }
+    di, report := s.instantDI(time, e.options.weightModel)
+    return di, report, nil
+}
+
+// Series returns DI series of each frequency from startTime until the last window covers endTime with their reports,
+// the issues are loaded once for all frequencies, and the reports are passed to the ReportFunc of WithReport too
+func (e *Engine) Series(repo, sig string, startTime, endTime time.Time, frequencies []time.Duration) ([]Series, error) {
+    specs := make([]WindowSpec, 0, len(frequencies))
+    for _, frequency := range frequencies {
+        specs = append(specs, Every(frequency))
+    }
+    return e.WindowSeries(repo, sig, startTime, endTime, specs)
+}
+
+// WindowSeries returns DI series of the windows of each spec covering startTime until endTime with their reports,
+// the issues are loaded once for all specs, and the reports are passed to the ReportFunc of WithReport too,
+// DI at a time shared by the series is reported once
+func (e *Engine) WindowSeries(repo, sig string, startTime, endTime time.Time, specs []WindowSpec) ([]Series, error) {
+    lists := make([][]Window, 0, len(specs))
+    first, last := startTime, endTime
+    for _, spec := range specs {
+        windows, err := spec.Windows(startTime, endTime)
+        if err != nil {
+            return nil, err
+        }
+        if len(windows) > 0 {
+            if windows[0].Start.Before(first) {
+                first = windows[0].Start
+            }
+            if windows[len(windows)-1].End.After(last) {
+                last = windows[len(windows)-1].End
+            }
+        }
+        lists = append(lists, windows)
+    }
+
+    s, err := e.snapshot(repo, sig, first, last, false)
+    if err != nil {
+        return nil, err
+    }
+    all := s.series(lists, e.options.weightModel)
+    reported := make(map[int64]bool)
+    for i := range all {
+        all[i].Spec = specs[i]
+        if specs[i].unit == fixedUnit {
+            all[i].Frequency = specs[i].duration
+        }
+        if e.options.reportFunc != nil {
+            all[i].report(e.options.reportFunc, reported)
+        }
+    }
+    return all, nil
+}
+
+// Breakdown returns DI at endTime and created, closed and reopened DI in [startTime, endTime) of each group of issues
+// by dimensions with the report of the issues, the issues are loaded once for all groups,
+// and the report is passed to the ReportFunc of WithReport too as metric DI_BREAKDOWN
+func (e *Engine) Breakdown(repo, sig string, dimensions []Dimension, startTime, endTime time.Time) ([]Breakdown, *Report, error) {
+    if err := checkDimensions(dimensions); err != nil {
+        return nil, nil, err
+    }
+    assignees := false
+    for _, dimension := range dimensions {
+        if dimension == DimensionAssignee {
+            assignees = true
+        }
+    }
+    s, err := e.snapshot(repo, sig, startTime, endTime, assignees)
+    if err != nil {
+        return nil, nil, err
+    }
+    breakdowns, report := s.breakdown(dimensions, startTime, endTime, e.options.weightModel)
+    e.options.report("DI_BREAKDOWN", startTime, endTime, report)
+    return breakdowns, report, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "io/ioutil"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// crawledIssues are issues fetched by crawler and saved in JSON:
+// issue 1 of tidb is closed on day 3, reopened on day 5 and closed again on day 8,
+// issue 2 of tikv is labeled critical on day 4 and issue 3 of tidb is created on day 6.
+const crawledIssues = `[
+    {
+        "DatabaseId": 1, "Number": 1, "Repository": {"Name": "tidb"},
+        "Closed": true, "ClosedAt": "2020-09-08T00:00:00Z", "CreatedAt": "2020-09-01T00:00:00Z",
+        "Labels": {"Nodes": [{"Name": "severity/major"}, {"Name": "sig/execution"}]},
+        "Assignees": {"Nodes": [{"Login": "alice"}]},
+        "TimelineItems": {"Nodes": [
+            {"Typename": "ClosedEvent", "ClosedEvent": {"CreatedAt": "2020-09-03T00:00:00Z"}},
+            {"Typename": "ReopenedEvent", "ReopenedEvent": {"CreatedAt": "2020-09-05T00:00:00Z"}},
+            {"Typename": "ClosedEvent", "ClosedEvent": {"CreatedAt": "2020-09-08T00:00:00Z"}}
+        ]}
+    },
+    {
+        "DatabaseId": 2, "Number": 2, "Repository": {"Name": "tikv"},
+        "Closed": false, "CreatedAt": "2020-09-02T00:00:00Z",
+        "Labels": {"Nodes": [{"Name": "severity/critical"}]},
+        "TimelineItems": {"Nodes": [
+            {"Typename": "LabeledEvent", "LabeledEvent": {"Label": {"Name": "severity/critical"}, "CreatedAt": "2020-09-04T00:00:00Z"}}
+        ]}
+    },
+    {
+        "DatabaseId": 3, "Number": 3, "Repository": {"Name": "tidb"},
+        "Closed": false, "CreatedAt": "2020-09-06T00:00:00Z",
+        "Labels": {"Nodes": [{"Name": "severity/minor"}, {"Name": "sig/planner"}]}
+    }
+]`
+
+func newTestSource(t *testing.T) *MemorySource {
+    path := filepath.Join(t.TempDir(), "issues.json")
+    must(t, ioutil.WriteFile(path, []byte(crawledIssues), 0644), nil, "err")
+    source, err := NewJSONSource(path)
+    must(t, err, nil, "err")
+    return source
+}
+
+func TestEngine(t *testing.T) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    engine := NewEngine(newTestSource(t))
+
+    di, _, err := engine.DI("tidb", "", day(4))
+    must(t, err, nil, "err")
+    must(t, di, 0.0, "di of tidb at day 4")
+    di, _, err = engine.DI("tidb", "", day(7))
+    must(t, err, nil, "err")
+    must(t, di, majorDI+minorDI, "di of tidb at day 7")
+    di, _, err = engine.CreatedDI("", "sig/execution", day(1), day(10))
+    must(t, err, nil, "err")
+    must(t, di, majorDI, "created di of sig/execution")
+    di, _, err = engine.ClosedDI("", "", day(1), day(10))
+    must(t, err, nil, "err")
+    must(t, di, 2*majorDI, "closed di")
+    di, _, err = engine.ReopenedDI("tidb", "", day(1), day(10))
+    must(t, err, nil, "err")
+    must(t, di, majorDI, "reopened di")
+    _, _, err = engine.CreatedDI("", "", day(10), day(1))
+    must(t, err != nil, true, "err != nil")
+
+    all, err := engine.Series("", "", day(1), day(10), []time.Duration{24 * time.Hour, 4 * 24 * time.Hour})
+    must(t, err, nil, "err")
+    for _, series := range all {
+        for _, instant := range series.Instant {
+            di, _, err := engine.DI("", "", instant.Time)
+            must(t, err, nil, "err")
+            must(t, instant.Value, di, "instant.Value")
+        }
+    }
+
+    breakdowns, _, err := engine.Breakdown("", "", []Dimension{DimensionAssignee}, day(1), day(10))
+    must(t, err, nil, "err")
+    must(t, len(breakdowns), 2, "len(breakdowns)")
+    must(t, breakdowns[1].Group[DimensionAssignee], "alice", "assignee")
+    must(t, breakdowns[1].ClosedDI, 2*majorDI, "closed di of alice")
+}
+
+func TestEngineLabelHistory(t *testing.T) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    source := newTestSource(t)
+
+    di, _, err := NewEngine(source).DI("tikv", "", day(3))
+    must(t, err, nil, "err")
+    must(t, di, criticalDI, "di of tikv at day 3")
+
+    // issue 2 is not labeled critical at day 3.
+    di, report, err := NewEngine(source, WithLabelHistory()).DI("tikv", "", day(3))
+    must(t, err, nil, "err")
+    must(t, di, 0.0, "di of tikv at day 3 with label history")
+    must(t, report.SkippedTotal(), 1, "report.SkippedTotal()")
+    di, _, err = NewEngine(source, WithLabelHistory()).DI("tikv", "", day(4))
+    must(t, err, nil, "err")
+    must(t, di, criticalDI, "di of tikv at day 4 with label history")
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// csvHeader is the header of the CSV written by CSVSink,
+// the group columns are the values of each dimension of a breakdown, which are empty for the other DI
+var csvHeader = []string{"metric", "repo", "sig", "group_repo", "group_sig", "group_severity", "group_component",
+    "group_assignee", "start_time", "end_time", "value"}
+
+// CSVSink define a Sink writing a CSV row of each DI as it is written, with a header before the first row
+type CSVSink struct {
+    recordSink
+    w      *csv.Writer
+    header bool
+}
+
+// NewCSVSink returns a CSVSink writing to w
+func NewCSVSink(w io.Writer) *CSVSink {
+    s := &CSVSink{w: csv.NewWriter(w)}
+    s.recordSink = recordSink{write: s.writeRecords}
+    return s
+}
+
+func (s *CSVSink) writeRecords(records []record) error {
+    if !s.header {
+        if err := s.w.Write(csvHeader); err != nil {
+            return err
+        }
+        s.header = true
+    }
+    for _, r := range records {
+        row := []string{r.Metric, r.Repo, r.SIG}
+        for _, column := range breakdownColumns {
+            row = append(row, r.Group[column])
+        }
+        row = append(row, r.StartTime.Format(time.RFC3339), r.EndTime.Format(time.RFC3339),
+            strconv.FormatFloat(r.Value, 'f', -1, 64))
+        if err := s.w.Write(row); err != nil {
+            return err
+        }
+    }
+    s.w.Flush()
+    return s.w.Error()
+}
+
+// JSONSink define a Sink writing all the DI written as a JSON array on Flush
+type JSONSink struct {
+    recordSink
+    w       io.Writer
+    records []record
+}
+
+// NewJSONSink returns a JSONSink writing to w
+func NewJSONSink(w io.Writer) *JSONSink {
+    s := &JSONSink{w: w}
+    s.recordSink = recordSink{write: s.writeRecords}
+    return s
+}
+
+func (s *JSONSink) writeRecords(records []record) error {
+    s.records = append(s.records, records...)
+    return nil
+}
+
+// Flush writes the DI written since the last Flush as a JSON array of objects with fields
+// metric, repo, sig, group, start_time, end_time and value
+func (s *JSONSink) Flush() error {
+    records := s.records
+    if records == nil {
+        records = make([]record, 0)
+    }
+    encoder := json.NewEncoder(s.w)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(records); err != nil {
+        return err
+    }
+    s.records = nil
+    return nil
+}
+
+// metricHelps are the HELP of the metrics written by PrometheusSink
+var metricHelps = map[string]string{
+    "DI":          "DI of the issues open at the time.",
+    "CREATED_DI":  "DI of the issues created in the window ending at the time.",
+    "CLOSED_DI":   "DI of the issues closed in the window ending at the time.",
+    "REOPENED_DI": "DI of the issues reopened in the window ending at the time.",
+}
+
+// PrometheusSink define a Sink writing all the DI written in the OpenMetrics text format on Flush,
+// which can be backfilled into Prometheus by "promtool tsdb create-blocks-from openmetrics".
+// Each metric is a gauge named by the metric in lower case, labeled by repo and sig,
+// and by dimensions and the value of each dimension, e.g. group_severity, for breakdowns.
+// A sample of DI of a window is at the end time of the window,
+// so series of one frequency should be written to a sink, or the samples at the same time are overwritten.
+type PrometheusSink struct {
+    recordSink
+    w       io.Writer
+    records []record
+}
+
+// NewPrometheusSink returns a PrometheusSink writing to w
+func NewPrometheusSink(w io.Writer) *PrometheusSink {
+    s := &PrometheusSink{w: w}
+    s.recordSink = recordSink{write: s.writeRecords}
+    return s
+}
+
+func (s *PrometheusSink) writeRecords(records []record) error {
+    s.records = append(s.records, records...)
+    return nil
+}
+
+// sample define a record in a metric of PrometheusSink
+type sample struct {
+    metric string
+    labels string
+    record record
+}
+
+// Flush writes the DI written since the last Flush, the samples of a metric are grouped,
+// and the samples of a series are in time order with the last one written at the same time kept
+func (s *PrometheusSink) Flush() error {
+    samples := make([]sample, 0, len(s.records))
+    for _, r := range s.records {
+        samples = append(samples, sample{metric: strings.ToLower(r.Metric), labels: promLabels(r), record: r})
+    }
+    sort.SliceStable(samples, func(i, j int) bool {
+        if samples[i].metric != samples[j].metric {
+            return samples[i].metric < samples[j].metric
+        }
+        if samples[i].labels != samples[j].labels {
+            return samples[i].labels < samples[j].labels
+        }
+        return samples[i].record.EndTime.Before(samples[j].record.EndTime)
+    })
+
+    w := bufio.NewWriter(s.w)
+    written := ""
+    for i, sample := range samples {
+        if i+1 < len(samples) && samples[i+1].metric == sample.metric && samples[i+1].labels == sample.labels &&
+            samples[i+1].record.EndTime.Equal(sample.record.EndTime) {
+            continue
+        }
+        if sample.metric != written {
+            written = sample.metric
+            if help, ok := metricHelps[sample.record.Metric]; ok {
+                fmt.Fprintf(w, "# HELP %s %s\n", sample.metric, help)
+            }
+            fmt.Fprintf(w, "# TYPE %s gauge\n", sample.metric)
+        }
+        fmt.Fprintf(w, "%s{%s} %s %s\n", sample.metric, sample.labels,
+            strconv.FormatFloat(sample.record.Value, 'f', -1, 64),
+            strconv.FormatFloat(float64(sample.record.EndTime.UnixNano())/float64(time.Second), 'f', -1, 64))
+    }
+    fmt.Fprint(w, "# EOF\n")
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    s.records = nil
+    return nil
+}
+
+// promLabels returns the labels of a record in the OpenMetrics text format
+func promLabels(r record) string {
+    labels := []string{promLabel("repo", r.Repo), promLabel("sig", r.SIG)}
+    if len(r.Group) > 0 {
+        dimensions := make([]string, 0, len(r.Group))
+        for dimension := range r.Group {
+            dimensions = append(dimensions, string(dimension))
+        }
+        sort.Strings(dimensions)
+        labels = append(labels, promLabel("dimensions", strings.Join(dimensions, ",")))
+        for _, dimension := range dimensions {
+            labels = append(labels, promLabel("group_"+dimension, r.Group[Dimension(dimension)]))
+        }
+    }
+    return strings.Join(labels, ",")
+}
+
+func promLabel(name, value string) string {
+    return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Filter define the issues DI is calculated on, empty fields match all issues
+type Filter struct {
+    // Repos are the names of repositories of issues
+    Repos []string
+    // SIGs are labels like "sig/execution", issues with any of them are matched
+    SIGs []string
+    // ExcludedLabels are labels like "duplicate" or "status/won't-fix", issues with any of them are not matched
+    ExcludedLabels []string
+    // LabelPrefixes are prefixes like "component/storage", issues with any label starting with one of them are matched
+    LabelPrefixes []string
+}
+
+// filterOf returns the filter of issues in repo with the sig label,
+// only non-empty repo and sig will be involved
+func filterOf(repo, sig string) Filter {
+    var filter Filter
+    if len(repo) > 0 {
+        filter.Repos = []string{repo}
+    }
+    if len(sig) > 0 {
+        filter.SIGs = []string{sig}
+    }
+    return filter
+}
+
+// generateQuery appends the conditions of filter to query of table ISSUE,
+// and returns the query with its arguments appended to args
+func generateQuery(query string, args []interface{}, filter Filter) (string, []interface{}) {
+    if len(filter.Repos) > 0 {
+        query += ` AND REPOSITORY_ID IN (
+                    SELECT ID
+                    FROM REPOSITORY
+                    WHERE REPO_NAME IN (` + placeholders(len(filter.Repos)) + `))`
+        args = appendStrings(args, filter.Repos)
+    }
+    if len(filter.SIGs) > 0 {
+        query += ` AND ID IN (` + labeledIssues(`LABEL.NAME IN (`+placeholders(len(filter.SIGs))+`)`) + `)`
+        args = appendStrings(args, filter.SIGs)
+    }
+    if len(filter.ExcludedLabels) > 0 {
+        query += ` AND ID NOT IN (` + labeledIssues(`LABEL.NAME IN (`+placeholders(len(filter.ExcludedLabels))+`)`) + `)`
+        args = appendStrings(args, filter.ExcludedLabels)
+    }
+    if len(filter.LabelPrefixes) > 0 {
+        conditions := make([]string, len(filter.LabelPrefixes))
+        for i, prefix := range filter.LabelPrefixes {
+            conditions[i] = `LABEL.NAME LIKE ?`
+            args = append(args, escapeLike(prefix)+"%")
+        }
+        query += ` AND ID IN (` + labeledIssues(strings.Join(conditions, " OR ")) + `)`
+    }
+    return query, args
+}
+
+// labeledIssues returns the query of IDs of issues with labels matched by condition
+func labeledIssues(condition string) string {
+    return `SELECT ISSUE_ID
+                    FROM LABEL_ISSUE_RELATIONSHIP
+                        LEFT JOIN LABEL ON LABEL_ISSUE_RELATIONSHIP.LABEL_ID = LABEL.ID
+                    WHERE ` + condition
+}
+
+// placeholders returns n comma separated placeholders
+func placeholders(n int) string {
+    return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func appendStrings(args []interface{}, values []string) []interface{} {
+    for _, value := range values {
+        args = append(args, value)
+    }
+    return args
+}
+
+// escapeLike escapes the wildcards of LIKE in s
+func escapeLike(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// tables DI can be stored in
+var tables = map[string]bool{
+    "DI":          true,
+    "CREATED_DI":  true,
+    "CLOSED_DI":   true,
+    "REOPENED_DI": true,
+}
+
+// checkTable returns an error if DI cannot be stored in table
+func checkTable(table string) error {
+    if !tables[table] {
+        return fmt.Errorf("unsupported DI table %q", table)
+    }
+    return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestGenerateQuery(t *testing.T) {
+    query, args := generateQuery("SELECT ID FROM ISSUE WHERE CLOSED = ?", []interface{}{0}, Filter{
+        Repos:          []string{"tidb", "tikv'; DROP TABLE ISSUE; --"},
+        SIGs:           []string{"sig/execution"},
+        ExcludedLabels: []string{"duplicate", "invalid"},
+        LabelPrefixes:  []string{"component/", "100%_"},
+    })
+    must(t, strings.Count(query, "?"), len(args), "placeholders")
+    must(t, strings.Contains(query, "DROP"), false, "query contains DROP")
+    must(t, fmt.Sprint(args), "[0 tidb tikv'; DROP TABLE ISSUE; -- sig/execution duplicate invalid component/% 100\\%\\_%]", "args")
+    must(t, strings.Contains(query, "ID NOT IN"), true, "query contains ID NOT IN")
+
+    query, args = generateQuery("SELECT ID FROM ISSUE WHERE CLOSED = 0", nil, filterOf("", ""))
+    must(t, query, "SELECT ID FROM ISSUE WHERE CLOSED = 0", "query")
+    must(t, len(args), 0, "len(args)")
+}
+
+func TestCheckTable(t *testing.T) {
+    must(t, checkTable("CREATED_DI"), nil, "checkTable(CREATED_DI)")
+    must(t, checkTable("DI; DROP TABLE DI") != nil, true, "checkTable(DI; DROP TABLE DI) != nil")
+
+    // an unsupported table is rejected before the transaction is used.
+    err := insertInstantDI(nil, "DI; DROP TABLE DI", "test", "", InstantDI{Time: time.Now(), Value: 20})
+    must(t, err != nil, true, "insertInstantDI(DI; DROP TABLE DI) != nil")
+    err = insertIntervalDI(nil, "DI; DROP TABLE DI", "test", "", IntervalDI{StartTime: time.Now(), EndTime: time.Now(), Value: 20})
+    must(t, err != nil, true, "insertIntervalDI(DI; DROP TABLE DI) != nil")
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Types of label events stored in table ISSUE_EVENT
+const (
+    labeledEventType   = "LabeledEvent"
+    unlabeledEventType = "UnlabeledEvent"
+)
+
+// LabelEvent define a label added to or removed from an issue
+type LabelEvent struct {
+    Time  time.Time
+    Name  string
+    Added bool
+}
+
+// labelsAt returns the names of labels issue had at t, sorted by name,
+// by undoing the label events after t on the current labels
+func labelsAt(issue Issue, t time.Time) []string {
+    labels := make(map[string]bool, len(issue.LabelNames))
+    for _, name := range issue.LabelNames {
+        labels[name] = true
+    }
+    // the events are in chronological order, undo the latest event first.
+    for i := len(issue.LabelEvents) - 1; i >= 0; i-- {
+        event := issue.LabelEvents[i]
+        if !event.Time.After(t) {
+            break
+        }
+        if event.Added {
+            delete(labels, event.Name)
+        } else {
+            labels[event.Name] = true
+        }
+    }
+
+    names := make([]string, 0, len(labels))
+    for name := range labels {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// matchLabels returns whether label names are matched by the label conditions of the filter
+func (f Filter) matchLabels(names []string) bool {
+    if len(f.SIGs) > 0 && !containsAny(names, f.SIGs) {
+        return false
+    }
+    if containsAny(names, f.ExcludedLabels) {
+        return false
+    }
+    if len(f.LabelPrefixes) > 0 {
+        for _, name := range names {
+            for _, prefix := range f.LabelPrefixes {
+                if strings.HasPrefix(name, prefix) {
+                    return true
+                }
+            }
+        }
+        return false
+    }
+    return true
+}
+
+// labelFree returns the filter without label conditions, which are matched by label history instead
+func (f Filter) labelFree() Filter {
+    return Filter{Repos: f.Repos}
+}
+
+func containsAny(names, values []string) bool {
+    for _, name := range names {
+        for _, value := range values {
+            if name == value {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// at returns issue with the labels it had at t, and whether it is matched by the filter at t.
+// The current labels are returned if the snapshot is loaded without label history.
+func (s *snapshot) at(issue Issue, t time.Time) (Issue, bool) {
+    if !s.history {
+        return issue, true
+    }
+    names := labelsAt(issue, t)
+    if !s.filter.matchLabels(names) {
+        return issue, false
+    }
+    issue.LabelNames = names
+    issue.Label = make(map[string][]string)
+    for _, name := range names {
+        addLabel(&issue, name)
+    }
+    return issue, true
+}
+
+// loadEvents loads the close and reopen events of the issues in batches to build their open intervals,
+// and the label events too if the snapshot is loaded with label history.
+// Without table ISSUE_EVENT, issues are open from CreatedAt to ClosedAt, and label history is not supported.
+func (s *snapshot) loadEvents(db *sql.DB) error {
+    index := make(map[uint]int, len(s.issues))
+    states := make([][]stateEvent, len(s.issues))
+    for i := range s.issues {
+        index[s.issues[i].ID] = i
+        s.issues[i].LabelEvents = nil
+    }
+    found, err := tableExists(db, "ISSUE_EVENT")
+    if err != nil {
+        return err
+    }
+    if !found {
+        if s.history {
+            return errors.New("label history requires table ISSUE_EVENT")
+        }
+        for i := range s.issues {
+            s.issues[i].Intervals = buildIntervals(s.issues[i], nil)
+        }
+        return nil
+    }
+    types := []interface{}{closedEventType, reopenedEventType}
+    if s.history {
+        types = append(types, labeledEventType, unlabeledEventType)
+    }
+
+    const batch = 1000
+    for start := 0; start < len(s.issues); start += batch {
+        end := start + batch
+        if end > len(s.issues) {
+            end = len(s.issues)
+        }
+        args := append([]interface{}{}, types...)
+        for _, issue := range s.issues[start:end] {
+            args = append(args, issue.ID)
+        }
+
+        if err := func() error {
+            ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
+            defer cancel()
+            rows, err := db.QueryContext(ctx, `SELECT ISSUE_ID, TYPE, LABEL, CREATED_AT FROM ISSUE_EVENT
+                                                WHERE TYPE IN (`+placeholders(len(types))+`) AND ISSUE_ID IN (`+placeholders(end-start)+`)
+                                                ORDER BY ISSUE_ID, SEQ`, args...)
+            if err != nil {
+                return err
+            }
+            defer rows.Close()
+            for rows.Next() {
+                var id uint
+                var eventType string
+                var event LabelEvent
+                var createdAt nullTime
+                if err := rows.Scan(&id, &eventType, &event.Name, &createdAt); err != nil {
+                    return err
+                }
+                i := index[id]
+                switch eventType {
+                case closedEventType, reopenedEventType:
+                    states[i] = append(states[i], stateEvent{time: createdAt.Time, closed: eventType == closedEventType})
+                default:
+                    event.Time = createdAt.Time
+                    event.Added = eventType == labeledEventType
+                    s.issues[i].LabelEvents = append(s.issues[i].LabelEvents, event)
+                }
+            }
+            return rows.Err()
+        }(); err != nil {
+            return err
+        }
+    }
+
+    for i := range s.issues {
+        s.issues[i].Intervals = buildIntervals(s.issues[i], states[i])
+    }
+    return nil
+}
+
+// tableExists returns whether table exists in the current database of db, the name is matched case-insensitively
+// as the server may store table names in lower case
+func tableExists(db *sql.DB, table string) (bool, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
+    defer cancel()
+    var count int
+    err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.TABLES
+                                    WHERE TABLE_SCHEMA = DATABASE() AND LOWER(TABLE_NAME) = LOWER(?)`, table).Scan(&count)
+    return count > 0, err
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+func TestLabelHistory(t *testing.T) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    // the issue is labeled critical on day 2, downgraded to minor on day 5 and moved from sig/planner on day 6.
+    issue := Issue{
+        Number:     1,
+        CreatedAt:  day(1),
+        LabelNames: []string{"severity/minor", "sig/execution"},
+        LabelEvents: []LabelEvent{
+            {Time: day(2), Name: "severity/critical", Added: true},
+            {Time: day(2), Name: "sig/planner", Added: true},
+            {Time: day(5), Name: "severity/critical"},
+            {Time: day(5), Name: "severity/minor", Added: true},
+            {Time: day(6), Name: "sig/planner"},
+            {Time: day(6), Name: "sig/execution", Added: true},
+        },
+    }
+    must(t, fmt.Sprint(labelsAt(issue, day(1))), "[]", "labelsAt(day 1)")
+    must(t, fmt.Sprint(labelsAt(issue, day(3))), "[severity/critical sig/planner]", "labelsAt(day 3)")
+    must(t, fmt.Sprint(labelsAt(issue, day(7))), "[severity/minor sig/execution]", "labelsAt(day 7)")
+
+    s := &snapshot{issues: []Issue{issue}, history: true, filter: Filter{SIGs: []string{"sig/planner"}}}
+    di, _ := s.instantDI(day(3), &DefaultWeightModel)
+    must(t, di, criticalDI, "di at day 3")
+    di, _ = s.instantDI(day(7), &DefaultWeightModel)
+    must(t, di, 0.0, "di at day 7")
+
+    all := s.series(windowsOf(t, day(1), day(7), []time.Duration{2 * 24 * time.Hour}), &DefaultWeightModel)
+    must(t, fmt.Sprint(all[0].Instant[1:]), fmt.Sprint([]InstantDI{{day(3), criticalDI}, {day(5), minorDI}, {day(7), 0}}), "instant series")
+    must(t, all[0].Created[0].Value, criticalDI, "created di of the first window")
+
+    must(t, Filter{ExcludedLabels: []string{"duplicate"}}.matchLabels([]string{"duplicate", "sig/planner"}), false, "excluded label matched")
+    must(t, Filter{LabelPrefixes: []string{"component/"}}.matchLabels([]string{"component/tikv"}), true, "label prefix matched")
+}
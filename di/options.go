@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "database/sql"
+    "time"
+)
+
+// Option configures the Process functions.
+type Option func(*options)
+
+type options struct {
+    replaceRange bool
+    weightModel  *WeightModel
+    reportFunc   ReportFunc
+    filter       *Filter
+    labelHistory bool
+    sink         Sink
+    windows      *WindowSpec
+}
+
+func newOptions(opts []Option) options {
+    o := options{weightModel: &DefaultWeightModel}
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o
+}
+
+// report passes the report of a DI calculation to the ReportFunc if there is one
+func (o *options) report(metric string, startTime, endTime time.Time, report *Report) {
+    if o.reportFunc != nil {
+        o.reportFunc(metric, startTime, endTime, report)
+    }
+}
+
+// filterOf returns the filter of WithFilter if there is one, or the filter of issues in repo with the sig label
+func (o *options) filterOf(repo, sig string) Filter {
+    if o.filter != nil {
+        return *o.filter
+    }
+    return filterOf(repo, sig)
+}
+
+// sinkOf returns the sink of WithSink if there is one, or the MySQLSink writing into diDB
+func (o *options) sinkOf(diDB *sql.DB) Sink {
+    if o.sink != nil {
+        return o.sink
+    }
+    return NewMySQLSink(diDB, o.replaceRange)
+}
+
+// windowsOf returns the spec of WithWindows if there is one, or the spec of windows stepping by frequency
+func (o *options) windowsOf(frequency time.Duration) WindowSpec {
+    if o.windows != nil {
+        return *o.windows
+    }
+    return Every(frequency)
+}
+
+// WithReplaceRange makes the Process functions delete the stored DIs of the repo and sig
+// in the processed time range before storing the new ones, in the same transaction.
+// By default the new DIs are upserted and the other stored DIs in the range are kept.
+func WithReplaceRange() Option {
+    return func(o *options) {
+        o.replaceRange = true
+    }
+}
+
+// WithWeightModel makes the Process functions weight issues by model instead of DefaultWeightModel.
+func WithWeightModel(model WeightModel) Option {
+    return func(o *options) {
+        o.weightModel = &model
+    }
+}
+
+// WithReport makes the Process functions pass the Report of each DI they store to fn,
+// e.g. DI at the start time and at the end of each window for ProcessDIs.
+// The series and breakdowns of Engine and the Compute functions are reported to fn too, with the reports of every metric,
+// a breakdown is reported once as metric DI_BREAKDOWN with the issues counted in its groups and skipped from all of them.
+func WithReport(fn ReportFunc) Option {
+    return func(o *options) {
+        o.reportFunc = fn
+    }
+}
+
+// WithFilter makes the Process functions calculate DI of issues matched by filter instead of repo and sig,
+// which then only name the DI stored, e.g. repo "storage" for DI of all the storage repos.
+func WithFilter(filter Filter) Option {
+    return func(o *options) {
+        o.filter = &filter
+    }
+}
+
+// WithLabelHistory makes the Process functions weight and filter issues by the labels they had at each evaluated time,
+// which are the current labels with the label events stored in table ISSUE_EVENT after the time undone,
+// so the Process functions return an error without the table.
+// DI at a time uses the labels at the time, and DI of a window uses the labels at the end of the window,
+// so the DI of the past does not change when labels are changed later.
+func WithLabelHistory() Option {
+    return func(o *options) {
+        o.labelHistory = true
+    }
+}
+
+// WithSink makes the Process functions write DI to sink instead of the tables of diDB, which may be nil then.
+// WithReplaceRange only applies to the tables of diDB.
+func WithSink(sink Sink) Option {
+    return func(o *options) {
+        o.sink = sink
+    }
+}
+
+// WithWindows makes the windowed Process functions, e.g. ProcessDIs, calculate DI of the windows of spec
+// instead of the windows stepping by frequency, which is ignored then.
+func WithWindows(spec WindowSpec) Option {
+    return func(o *options) {
+        o.windows = &spec
+    }
+}
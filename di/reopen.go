@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "time"
+)
+
+// Types of close and reopen events stored in table ISSUE_EVENT
+const (
+    closedEventType   = "ClosedEvent"
+    reopenedEventType = "ReopenedEvent"
+)
+
+// Interval define a period an issue is open, from its creation or a reopen event to a close event
+type Interval struct {
+    Start time.Time
+    // End is zero if the issue is still open
+    End time.Time
+}
+
+// openAt returns whether the issue is open at t in the interval, i.e. opened before t and not closed before t
+func (i Interval) openAt(t time.Time) bool {
+    return i.Start.Before(t) && (i.End.IsZero() || !i.End.Before(t))
+}
+
+// stateEvent define an issue closed or reopened at a time
+type stateEvent struct {
+    time   time.Time
+    closed bool
+}
+
+// intervals returns the open intervals of issue in chronological order.
+// Issues loaded without close and reopen events are open from CreatedAt to ClosedAt.
+func (issue *Issue) intervals() []Interval {
+    if issue.Intervals != nil {
+        return issue.Intervals
+    }
+    interval := Interval{Start: issue.CreatedAt}
+    if issue.Closed {
+        interval.End = issue.ClosedAt
+    }
+    return []Interval{interval}
+}
+
+// buildIntervals returns the open intervals of issue by its close and reopen events in chronological order
+func buildIntervals(issue Issue, events []stateEvent) []Interval {
+    intervals := []Interval{{Start: issue.CreatedAt}}
+    open := true
+    for _, event := range events {
+        switch {
+        case event.closed && open:
+            intervals[len(intervals)-1].End = event.time
+            open = false
+        case !event.closed && !open:
+            intervals = append(intervals, Interval{Start: event.time})
+            open = true
+        }
+    }
+    // the issue is closed without a stored close event.
+    if open && issue.Closed && !issue.ClosedAt.Before(intervals[len(intervals)-1].Start) {
+        intervals[len(intervals)-1].End = issue.ClosedAt
+    }
+    return intervals
+}
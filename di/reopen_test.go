@@ -0,0 +1,66 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+func TestReopen(t *testing.T) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    // the issue is closed on day 3, reopened on day 5 and closed again on day 8.
+    issue := Issue{Number: 1, CreatedAt: day(1), Closed: true, ClosedAt: day(8), Label: make(map[string][]string)}
+    addLabel(&issue, "severity/major")
+    issue.Intervals = buildIntervals(issue, []stateEvent{
+        {time: day(3), closed: true},
+        {time: day(5)},
+        // a duplicated close event is ignored.
+        {time: day(5), closed: true},
+        {time: day(5), closed: true},
+        {time: day(6)},
+        {time: day(8), closed: true},
+    })
+    must(t, fmt.Sprint(issue.Intervals), fmt.Sprint([]Interval{{day(1), day(3)}, {day(5), day(5)}, {day(6), day(8)}}), "intervals")
+
+    s := &snapshot{issues: []Issue{issue}}
+    di, _ := s.instantDI(day(4), &DefaultWeightModel)
+    must(t, di, 0.0, "di at day 4")
+    di, _ = s.instantDI(day(7), &DefaultWeightModel)
+    must(t, di, majorDI, "di at day 7")
+    di, _ = s.closedDI(day(1), day(10), &DefaultWeightModel)
+    must(t, di, 3*majorDI, "closed di")
+    di, _ = s.reopenedDI(day(1), day(10), &DefaultWeightModel)
+    must(t, di, 2*majorDI, "reopened di")
+    di, _ = s.reopenedDI(day(6), day(10), &DefaultWeightModel)
+    must(t, di, majorDI, "reopened di since day 6")
+
+    all := s.series(windowsOf(t, day(1), day(9), []time.Duration{2 * 24 * time.Hour}), &DefaultWeightModel)
+    for i, reopened := range all[0].Reopened {
+        di, _ := s.reopenedDI(reopened.StartTime, reopened.EndTime, &DefaultWeightModel)
+        must(t, reopened.Value, di, "reopened.Value")
+        di, _ = s.closedDI(all[0].Closed[i].StartTime, all[0].Closed[i].EndTime, &DefaultWeightModel)
+        must(t, all[0].Closed[i].Value, di, "closed.Value")
+    }
+    for _, instant := range all[0].Instant {
+        di, _ := s.instantDI(instant.Time, &DefaultWeightModel)
+        must(t, instant.Value, di, "instant.Value")
+    }
+
+    // an issue without close and reopen events is open until it is closed.
+    issue = Issue{CreatedAt: day(1), Closed: true, ClosedAt: day(3)}
+    must(t, fmt.Sprint(issue.intervals()), fmt.Sprint([]Interval{{day(1), day(3)}}), "intervals without events")
+    must(t, fmt.Sprint(buildIntervals(issue, nil)), fmt.Sprint([]Interval{{day(1), day(3)}}), "intervals built without events")
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "errors"
+    "sort"
+    "time"
+)
+
+// SkipReason define why an issue is not counted in DI
+type SkipReason string
+
+// Reasons of skipped issues
+const (
+    SkipNoSeverity         SkipReason = "no severity"
+    SkipMultipleSeverities SkipReason = "multiple severities"
+    SkipUnknownSeverity    SkipReason = "unsupported severity"
+)
+
+// Report define the issues counted and skipped by a DI calculation
+type Report struct {
+    // Counted is the number of issues counted by severity,
+    // issues weighted by the fallback weight of WeightModel are counted in "",
+    // and issues weighted by multiple severities are counted in each of them.
+    Counted map[string]int
+    // Skipped is the sorted numbers of issues skipped by reason
+    Skipped map[SkipReason][]int
+}
+
+// ReportFunc receives the Report of a DI calculation of the Process functions.
+// metric is the table DI is stored in, and startTime equals endTime for DI at an instant.
+type ReportFunc func(metric string, startTime, endTime time.Time, report *Report)
+
+func newReport() *Report {
+    return &Report{
+        Counted: make(map[string]int),
+        Skipped: make(map[SkipReason][]int),
+    }
+}
+
+// CountedTotal returns the number of issues counted
+func (r *Report) CountedTotal() int {
+    total := 0
+    for _, count := range r.Counted {
+        total += count
+    }
+    return total
+}
+
+// SkippedTotal returns the number of issues skipped
+func (r *Report) SkippedTotal() int {
+    total := 0
+    for _, numbers := range r.Skipped {
+        total += len(numbers)
+    }
+    return total
+}
+
+// Merge adds the issues of other to r, skipped issues in both reports are only kept once.
+func (r *Report) Merge(other *Report) {
+    for severity, count := range other.Counted {
+        r.Counted[severity] += count
+    }
+    for reason, numbers := range other.Skipped {
+        for _, number := range numbers {
+            r.skip(reason, number)
+        }
+    }
+}
+
+// count adds an issue weighted by severities
+func (r *Report) count(severities []string) {
+    if len(severities) == 0 {
+        r.Counted[""]++
+    }
+    for _, severity := range severities {
+        r.Counted[severity]++
+    }
+}
+
+// skip adds an issue skipped by reason
+func (r *Report) skip(reason SkipReason, number int) {
+    numbers := r.Skipped[reason]
+    i := sort.SearchInts(numbers, number)
+    if i < len(numbers) && numbers[i] == number {
+        return
+    }
+    numbers = append(numbers, 0)
+    copy(numbers[i+1:], numbers[i:])
+    numbers[i] = number
+    r.Skipped[reason] = numbers
+}
+
+// skipReason returns the SkipReason of err returned by WeightModel.Weight
+func skipReason(err error) SkipReason {
+    switch {
+    case errors.Is(err, ErrNoSeverity):
+        return SkipNoSeverity
+    case errors.Is(err, ErrMultipleSeverities):
+        return SkipMultipleSeverities
+    case errors.Is(err, ErrUnknownSeverity):
+        return SkipUnknownSeverity
+    default:
+        return SkipReason(err.Error())
+    }
+}
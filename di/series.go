@@ -0,0 +1,252 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "database/sql"
+    "sort"
+    "time"
+)
+
+// Series define DI of consecutive windows of Spec,
+// a window includes its start time and excludes its end time
+type Series struct {
+    Spec WindowSpec
+    // Frequency is the duration of the windows of a spec of Every, or 0 for windows of the calendar
+    Frequency time.Duration
+    // Instant is DI at the start time and at the end time of each window
+    Instant []InstantDI
+    // Created is DI of issues created in each window
+    Created []IntervalDI
+    // Closed is DI of issues closed in each window
+    Closed []IntervalDI
+    // Reopened is DI of issues reopened in each window
+    Reopened []IntervalDI
+    // InstantReports, CreatedReports, ClosedReports and ReopenedReports are the reports of the DI
+    // at the same index of Instant, Created, Closed and Reopened
+    InstantReports  []*Report
+    CreatedReports  []*Report
+    ClosedReports   []*Report
+    ReopenedReports []*Report
+}
+
+// eventKind define how an issueEvent changes the state of an issue
+type eventKind int
+
+const (
+    createdEvent eventKind = iota
+    closedEvent
+    reopenedEvent
+)
+
+// issueEvent define an issue created, closed or reopened at a time
+type issueEvent struct {
+    time  time.Time
+    kind  eventKind
+    issue int
+}
+
+// weightedIssue define an issue weighted by a WeightModel
+type weightedIssue struct {
+    di         float64
+    severities []string
+    err        error
+}
+
+// events returns the created, closed and reopened events of the issues by their open intervals in time order
+func (s *snapshot) events() []issueEvent {
+    events := make([]issueEvent, 0, 2*len(s.issues))
+    for i := range s.issues {
+        for j, interval := range s.issues[i].intervals() {
+            kind := reopenedEvent
+            if j == 0 {
+                kind = createdEvent
+            }
+            events = append(events, issueEvent{time: interval.Start, kind: kind, issue: i})
+            if !interval.End.IsZero() {
+                events = append(events, issueEvent{time: interval.End, kind: closedEvent, issue: i})
+            }
+        }
+    }
+    sort.SliceStable(events, func(i, j int) bool {
+        return events[i].time.Before(events[j].time)
+    })
+    return events
+}
+
+// weigh returns the issues weighted by model
+func (s *snapshot) weigh(model *WeightModel) []weightedIssue {
+    weighted := make([]weightedIssue, len(s.issues))
+    for i, issue := range s.issues {
+        weighted[i].di, weighted[i].severities, weighted[i].err = model.weigh(issue.Label)
+    }
+    return weighted
+}
+
+// series returns DI series of each list of consecutive windows with their reports,
+// the issues are weighted once and their events are swept once for each list.
+func (s *snapshot) series(lists [][]Window, model *WeightModel) []Series {
+    if s.history {
+        return s.seriesAt(lists, model)
+    }
+    weighted := s.weigh(model)
+    events := s.events()
+    add := func(r *Report, issue int) {
+        if err := weighted[issue].err; err != nil {
+            r.skip(skipReason(err), s.issues[issue].Number)
+        } else {
+            r.count(weighted[issue].severities)
+        }
+    }
+    openReport := func(open map[int]bool) *Report {
+        r := newReport()
+        for issue := range open {
+            add(r, issue)
+        }
+        return r
+    }
+
+    all := make([]Series, 0, len(lists))
+    for _, windows := range lists {
+        var series Series
+        if len(windows) == 0 {
+            all = append(all, series)
+            continue
+        }
+
+        // DI at the start time, by the events before it.
+        startTime := windows[0].Start
+        value := 0.0
+        open := make(map[int]bool)
+        next := 0
+        for ; next < len(events) && events[next].time.Before(startTime); next++ {
+            event := events[next]
+            if event.kind == closedEvent {
+                delete(open, event.issue)
+                value -= weighted[event.issue].di
+            } else {
+                open[event.issue] = true
+                value += weighted[event.issue].di
+            }
+        }
+        series.Instant = []InstantDI{{Time: startTime, Value: value}}
+        series.InstantReports = []*Report{openReport(open)}
+
+        for _, window := range windows {
+            created, closed, reopened := 0.0, 0.0, 0.0
+            createdReport, closedReport, reopenedReport := newReport(), newReport(), newReport()
+            for ; next < len(events) && events[next].time.Before(window.End); next++ {
+                event := events[next]
+                switch event.kind {
+                case createdEvent:
+                    created += weighted[event.issue].di
+                    add(createdReport, event.issue)
+                    open[event.issue] = true
+                case closedEvent:
+                    closed += weighted[event.issue].di
+                    add(closedReport, event.issue)
+                    delete(open, event.issue)
+                case reopenedEvent:
+                    reopened += weighted[event.issue].di
+                    add(reopenedReport, event.issue)
+                    open[event.issue] = true
+                }
+            }
+            value += created + reopened - closed
+
+            series.Instant = append(series.Instant, InstantDI{Time: window.End, Value: value})
+            series.Created = append(series.Created, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: created})
+            series.Closed = append(series.Closed, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: closed})
+            series.Reopened = append(series.Reopened, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: reopened})
+            series.InstantReports = append(series.InstantReports, openReport(open))
+            series.CreatedReports = append(series.CreatedReports, createdReport)
+            series.ClosedReports = append(series.ClosedReports, closedReport)
+            series.ReopenedReports = append(series.ReopenedReports, reopenedReport)
+        }
+        all = append(all, series)
+    }
+    return all
+}
+
+// seriesAt returns the same series as series, but the issues are weighted with their labels at each time,
+// so DI of each time and window is calculated separately
+func (s *snapshot) seriesAt(lists [][]Window, model *WeightModel) []Series {
+    all := make([]Series, 0, len(lists))
+    for _, windows := range lists {
+        var series Series
+        if len(windows) == 0 {
+            all = append(all, series)
+            continue
+        }
+
+        startTime := windows[0].Start
+        instant, instantReport := s.instantDI(startTime, model)
+        series.Instant = []InstantDI{{Time: startTime, Value: instant}}
+        series.InstantReports = []*Report{instantReport}
+
+        for _, window := range windows {
+            created, createdReport := s.createdDI(window.Start, window.End, model)
+            closed, closedReport := s.closedDI(window.Start, window.End, model)
+            reopened, reopenedReport := s.reopenedDI(window.Start, window.End, model)
+            instant, instantReport := s.instantDI(window.End, model)
+
+            series.Instant = append(series.Instant, InstantDI{Time: window.End, Value: instant})
+            series.Created = append(series.Created, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: created})
+            series.Closed = append(series.Closed, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: closed})
+            series.Reopened = append(series.Reopened, IntervalDI{StartTime: window.Start, EndTime: window.End, Value: reopened})
+            series.InstantReports = append(series.InstantReports, instantReport)
+            series.CreatedReports = append(series.CreatedReports, createdReport)
+            series.ClosedReports = append(series.ClosedReports, closedReport)
+            series.ReopenedReports = append(series.ReopenedReports, reopenedReport)
+        }
+        all = append(all, series)
+    }
+    return all
+}
+
+// report passes the reports of each DI of the series to fn,
+// DI at a time in reported is skipped, and the times of DI reported are added to reported
+func (series Series) report(fn ReportFunc, reported map[int64]bool) {
+    for i, instant := range series.Instant {
+        if !reported[instant.Time.UnixNano()] {
+            reported[instant.Time.UnixNano()] = true
+            fn("DI", instant.Time, instant.Time, series.InstantReports[i])
+        }
+    }
+    for i, created := range series.Created {
+        fn("CREATED_DI", created.StartTime, created.EndTime, series.CreatedReports[i])
+    }
+    for i, closed := range series.Closed {
+        fn("CLOSED_DI", closed.StartTime, closed.EndTime, series.ClosedReports[i])
+    }
+    for i, reopened := range series.Reopened {
+        fn("REOPENED_DI", reopened.StartTime, reopened.EndTime, series.ReopenedReports[i])
+    }
+}
+
+// ComputeDISeries returns DI series of each frequency from startTime until the last window covers endTime with their reports,
+// the issues are loaded from issueDB once for all frequencies
+// only non-empty repo and sig will be involved, unless there is a filter of WithFilter
+func ComputeDISeries(issueDB *sql.DB, repo, sig string, startTime, endTime time.Time, frequencies []time.Duration,
+    opts ...Option) ([]Series, error) {
+    return NewEngine(NewSQLSource(issueDB), opts...).Series(repo, sig, startTime, endTime, frequencies)
+}
+
+// ComputeWindowSeries returns DI series of the windows of each spec covering startTime until endTime with their reports,
+// the issues are loaded from issueDB once for all specs
+// only non-empty repo and sig will be involved, unless there is a filter of WithFilter
+func ComputeWindowSeries(issueDB *sql.DB, repo, sig string, startTime, endTime time.Time, specs []WindowSpec,
+    opts ...Option) ([]Series, error) {
+    return NewEngine(NewSQLSource(issueDB), opts...).WindowSeries(repo, sig, startTime, endTime, specs)
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+// windowsOf returns the windows stepping by each frequency from startTime until the last window covers endTime
+func windowsOf(t *testing.T, startTime, endTime time.Time, frequencies []time.Duration) [][]Window {
+    lists := make([][]Window, 0, len(frequencies))
+    for _, frequency := range frequencies {
+        windows, err := Every(frequency).Windows(startTime, endTime)
+        must(t, err, nil, "err")
+        lists = append(lists, windows)
+    }
+    return lists
+}
+
+func TestSnapshotSeries(t *testing.T) {
+    s := newTestSnapshot()
+    startTime := time.Date(2020, 9, 2, 0, 0, 0, 0, time.UTC)
+    endTime := time.Date(2020, 9, 12, 0, 0, 0, 0, time.UTC)
+    frequencies := []time.Duration{24 * time.Hour, 3 * 24 * time.Hour, 7 * 24 * time.Hour}
+
+    all := s.series(windowsOf(t, startTime, endTime, frequencies), &DefaultWeightModel)
+    must(t, len(all), 3, "len(all)")
+    reports, reported := 0, make(map[int64]bool)
+    for _, series := range all {
+        series.report(func(metric string, startTime, endTime time.Time, report *Report) {
+            reports++
+        }, reported)
+    }
+    // DI at 2020-09-02 until 2020-09-12, 2020-09-14 and 2020-09-16 is reported once.
+    must(t, reports, 13+3*(10+4+2), "reports")
+
+    // the sweep gives the same DI as calculating each window.
+    for _, series := range all {
+        must(t, len(series.Instant), len(series.Created)+1, "len(series.Instant)")
+        for i, created := range series.Created {
+            di, report := s.createdDI(created.StartTime, created.EndTime, &DefaultWeightModel)
+            must(t, created.Value, di, "created.Value")
+            must(t, fmt.Sprint(series.CreatedReports[i]), fmt.Sprint(report), "series.CreatedReports[i]")
+            di, _ = s.closedDI(series.Closed[i].StartTime, series.Closed[i].EndTime, &DefaultWeightModel)
+            must(t, series.Closed[i].Value, di, "closed.Value")
+            di, _ = s.reopenedDI(series.Reopened[i].StartTime, series.Reopened[i].EndTime, &DefaultWeightModel)
+            must(t, series.Reopened[i].Value, di, "reopened.Value")
+        }
+        for i, instant := range series.Instant {
+            di, report := s.instantDI(instant.Time, &DefaultWeightModel)
+            must(t, instant.Value, di, "instant.Value")
+            must(t, fmt.Sprint(series.InstantReports[i]), fmt.Sprint(report), "series.InstantReports[i]")
+        }
+    }
+    must(t, all[2].Instant[2].Time, time.Date(2020, 9, 16, 0, 0, 0, 0, time.UTC), "all[2].Instant[2].Time")
+    must(t, all[2].Instant[2].Value, majorDI, "all[2].Instant[2].Value")
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "database/sql"
+    "errors"
+    "time"
+)
+
+// Sink define where the Process functions write DI,
+// metric is the kind of DI written, which is "DI", "CREATED_DI", "CLOSED_DI" or "REOPENED_DI"
+type Sink interface {
+    // WriteIntervalDI writes DI of windows of repo and sig
+    WriteIntervalDI(metric, repo, sig string, dis []IntervalDI) error
+    // WriteInstantDI writes DI at times of repo and sig
+    WriteInstantDI(metric, repo, sig string, dis []InstantDI) error
+    // WriteBreakdown writes the breakdown of DI by dimensions of repo and sig in [startTime, endTime)
+    WriteBreakdown(repo, sig string, dimensions []Dimension, startTime, endTime time.Time, breakdowns []Breakdown) error
+}
+
+// MySQLSink define a Sink writing DI into the tables of the same names as the metrics,
+// and breakdowns into table DI_BREAKDOWN
+type MySQLSink struct {
+    db           *sql.DB
+    replaceRange bool
+}
+
+// NewMySQLSink returns a MySQLSink writing into db,
+// the stored DI in the range written are deleted first if replaceRange is true
+func NewMySQLSink(db *sql.DB, replaceRange bool) *MySQLSink {
+    return &MySQLSink{db: db, replaceRange: replaceRange}
+}
+
+// WriteIntervalDI implements the Sink interface
+func (s *MySQLSink) WriteIntervalDI(metric, repo, sig string, dis []IntervalDI) error {
+    if s.db == nil {
+        return errors.New("db is nil")
+    }
+    return storeIntervalDI(s.db, metric, repo, sig, dis, s.replaceRange)
+}
+
+// WriteInstantDI implements the Sink interface
+func (s *MySQLSink) WriteInstantDI(metric, repo, sig string, dis []InstantDI) error {
+    if s.db == nil {
+        return errors.New("db is nil")
+    }
+    return storeInstantDI(s.db, metric, repo, sig, dis, s.replaceRange)
+}
+
+// WriteBreakdown implements the Sink interface
+func (s *MySQLSink) WriteBreakdown(repo, sig string, dimensions []Dimension, startTime, endTime time.Time,
+    breakdowns []Breakdown) error {
+    if s.db == nil {
+        return errors.New("db is nil")
+    }
+    return storeBreakdown(s.db, repo, sig, dimensions, startTime, endTime, breakdowns, s.replaceRange)
+}
+
+// record define a DI value written by the file sinks,
+// the start time and end time of DI at a time are both the time
+type record struct {
+    Metric    string               `json:"metric"`
+    Repo      string               `json:"repo"`
+    SIG       string               `json:"sig"`
+    Group     map[Dimension]string `json:"group,omitempty"`
+    StartTime time.Time            `json:"start_time"`
+    EndTime   time.Time            `json:"end_time"`
+    Value     float64              `json:"value"`
+}
+
+// recordSink implements Sink by converting DI into records passed to write
+type recordSink struct {
+    write func(records []record) error
+}
+
+// WriteIntervalDI implements the Sink interface
+func (s recordSink) WriteIntervalDI(metric, repo, sig string, dis []IntervalDI) error {
+    records := make([]record, 0, len(dis))
+    for _, di := range dis {
+        records = append(records, record{Metric: metric, Repo: repo, SIG: sig,
+            StartTime: di.StartTime, EndTime: di.EndTime, Value: di.Value})
+    }
+    return s.write(records)
+}
+
+// WriteInstantDI implements the Sink interface
+func (s recordSink) WriteInstantDI(metric, repo, sig string, dis []InstantDI) error {
+    records := make([]record, 0, len(dis))
+    for _, di := range dis {
+        records = append(records, record{Metric: metric, Repo: repo, SIG: sig,
+            StartTime: di.Time, EndTime: di.Time, Value: di.Value})
+    }
+    return s.write(records)
+}
+
+// WriteBreakdown implements the Sink interface, each group is written as DI at endTime
+// and created, closed and reopened DI in [startTime, endTime)
+func (s recordSink) WriteBreakdown(repo, sig string, dimensions []Dimension, startTime, endTime time.Time,
+    breakdowns []Breakdown) error {
+    records := make([]record, 0, 4*len(breakdowns))
+    for _, breakdown := range breakdowns {
+        records = append(records,
+            record{Metric: "DI", Repo: repo, SIG: sig, Group: breakdown.Group,
+                StartTime: endTime, EndTime: endTime, Value: breakdown.DI},
+            record{Metric: "CREATED_DI", Repo: repo, SIG: sig, Group: breakdown.Group,
+                StartTime: startTime, EndTime: endTime, Value: breakdown.CreatedDI},
+            record{Metric: "CLOSED_DI", Repo: repo, SIG: sig, Group: breakdown.Group,
+                StartTime: startTime, EndTime: endTime, Value: breakdown.ClosedDI},
+            record{Metric: "REOPENED_DI", Repo: repo, SIG: sig, Group: breakdown.Group,
+                StartTime: startTime, EndTime: endTime, Value: breakdown.ReopenedDI})
+    }
+    return s.write(records)
+}
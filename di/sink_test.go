@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "bytes"
+    "encoding/json"
+    "strings"
+    "testing"
+    "time"
+)
+
+// writeTestDI writes created DI of two windows, DI at a time and a breakdown by severity to sink
+func writeTestDI(t *testing.T, sink Sink) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    err := sink.WriteIntervalDI("CREATED_DI", "tidb", "", []IntervalDI{{day(8), day(15), 3.1}, {day(1), day(8), 10}})
+    must(t, err, nil, "err")
+    err = sink.WriteInstantDI("DI", "tidb", "", []InstantDI{{day(8), 0.1}})
+    must(t, err, nil, "err")
+    err = sink.WriteBreakdown("tidb", "", []Dimension{DimensionSeverity}, day(1), day(8),
+        []Breakdown{{Group: map[Dimension]string{DimensionSeverity: "major"}, DI: 3, CreatedDI: 6, ClosedDI: 3}})
+    must(t, err, nil, "err")
+}
+
+func TestCSVSink(t *testing.T) {
+    var buf bytes.Buffer
+    writeTestDI(t, NewCSVSink(&buf))
+    lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+    must(t, len(lines), 1+2+1+4, "len(lines)")
+    must(t, lines[0], strings.Join(csvHeader, ","), "header")
+    must(t, lines[1], "CREATED_DI,tidb,,,,,,,2020-09-08T00:00:00Z,2020-09-15T00:00:00Z,3.1", "lines[1]")
+    must(t, lines[3], "DI,tidb,,,,,,,2020-09-08T00:00:00Z,2020-09-08T00:00:00Z,0.1", "lines[3]")
+    must(t, lines[5], "CREATED_DI,tidb,,,,major,,,2020-09-01T00:00:00Z,2020-09-08T00:00:00Z,6", "lines[5]")
+}
+
+func TestJSONSink(t *testing.T) {
+    var buf bytes.Buffer
+    sink := NewJSONSink(&buf)
+    writeTestDI(t, sink)
+    must(t, buf.Len(), 0, "buf.Len() before Flush")
+    must(t, sink.Flush(), nil, "err")
+
+    var records []record
+    must(t, json.Unmarshal(buf.Bytes(), &records), nil, "err")
+    must(t, len(records), 2+1+4, "len(records)")
+    must(t, records[0].Metric, "CREATED_DI", "records[0].Metric")
+    must(t, records[0].Value, 3.1, "records[0].Value")
+    must(t, records[3].Group[DimensionSeverity], "major", "records[3].Group")
+}
+
+func TestPrometheusSink(t *testing.T) {
+    var buf bytes.Buffer
+    sink := NewPrometheusSink(&buf)
+    writeTestDI(t, sink)
+    // the rewritten sample replaces the sample at the same time.
+    err := sink.WriteInstantDI("DI", "tidb", "", []InstantDI{{time.Date(2020, 9, 8, 0, 0, 0, 0, time.UTC), 0.2}})
+    must(t, err, nil, "err")
+    must(t, sink.Flush(), nil, "err")
+
+    expected := `# HELP closed_di DI of the issues closed in the window ending at the time.
+# TYPE closed_di gauge
+closed_di{repo="tidb",sig="",dimensions="severity",group_severity="major"} 3 1599523200
+# HELP created_di DI of the issues created in the window ending at the time.
+# TYPE created_di gauge
+created_di{repo="tidb",sig=""} 10 1599523200
+created_di{repo="tidb",sig=""} 3.1 1600128000
+created_di{repo="tidb",sig="",dimensions="severity",group_severity="major"} 6 1599523200
+# HELP di DI of the issues open at the time.
+# TYPE di gauge
+di{repo="tidb",sig=""} 0.2 1599523200
+di{repo="tidb",sig="",dimensions="severity",group_severity="major"} 3 1599523200
+# HELP reopened_di DI of the issues reopened in the window ending at the time.
+# TYPE reopened_di gauge
+reopened_di{repo="tidb",sig="",dimensions="severity",group_severity="major"} 0 1599523200
+# EOF
+`
+    must(t, buf.String(), expected, "exposition")
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+)
+
+// snapshot define issues loaded with their labels,
+// DI of any time or window covered by the loaded issues is calculated without querying again
+type snapshot struct {
+    issues []Issue
+    // history is whether labels of issues are evaluated at each time by their label events,
+    // and the label conditions of filter are matched by the labels at the time
+    history bool
+    filter  Filter
+}
+
+// loadSnapshot loads issues matched by where and filter with their labels in a single query,
+// and then their close and reopen events, with their label events too if history is true
+func loadSnapshot(db *sql.DB, where string, filter Filter, history bool, args ...interface{}) (*snapshot, error) {
+    if db == nil {
+        return nil, errors.New("db is nil")
+    }
+    s := &snapshot{issues: make([]Issue, 0), history: history, filter: filter}
+    if history {
+        filter = filter.labelFree()
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
+    defer cancel()
+
+    issues, args := generateQuery("SELECT ID, NUMBER, REPOSITORY_ID, CLOSED, CLOSED_AT, CREATED_AT FROM ISSUE WHERE "+where, args, filter)
+    query := `SELECT I.ID, I.NUMBER, I.REPOSITORY_ID, REPOSITORY.REPO_NAME, I.CLOSED, I.CLOSED_AT, I.CREATED_AT, LABEL.NAME
+                FROM (` + issues + `) AS I
+                    LEFT JOIN REPOSITORY ON REPOSITORY.ID = I.REPOSITORY_ID
+                    LEFT JOIN LABEL_ISSUE_RELATIONSHIP ON LABEL_ISSUE_RELATIONSHIP.ISSUE_ID = I.ID
+                    LEFT JOIN LABEL ON LABEL_ISSUE_RELATIONSHIP.LABEL_ID = LABEL.ID
+                ORDER BY I.ID`
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var issue Issue
+        var closedAt, createdAt nullTime
+        var repoName, label sql.NullString
+        if err := rows.Scan(&issue.ID, &issue.Number, &issue.RepositoryID, &repoName, &issue.Closed, &closedAt, &createdAt, &label); err != nil {
+            return nil, err
+        }
+
+        if n := len(s.issues); n == 0 || s.issues[n-1].ID != issue.ID {
+            issue.RepoName = repoName.String
+            issue.ClosedAt = closedAt.Time
+            issue.CreatedAt = createdAt.Time
+            issue.Label = make(map[string][]string)
+            s.issues = append(s.issues, issue)
+        }
+        if label.Valid {
+            last := &s.issues[len(s.issues)-1]
+            last.LabelNames = append(last.LabelNames, label.String)
+            addLabel(last, label.String)
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    if err := s.loadEvents(db); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+// loadAssignees loads the assignees of the issues in batches
+func (s *snapshot) loadAssignees(db *sql.DB) error {
+    index := make(map[uint]int, len(s.issues))
+    for i := range s.issues {
+        index[s.issues[i].ID] = i
+        s.issues[i].Assignees = nil
+    }
+
+    const batch = 1000
+    for start := 0; start < len(s.issues); start += batch {
+        end := start + batch
+        if end > len(s.issues) {
+            end = len(s.issues)
+        }
+        ids := make([]interface{}, 0, end-start)
+        for _, issue := range s.issues[start:end] {
+            ids = append(ids, issue.ID)
+        }
+
+        if err := func() error {
+            ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
+            defer cancel()
+            rows, err := db.QueryContext(ctx, `SELECT ISSUE_ID, LOGIN FROM ISSUE_ASSIGNEE WHERE ISSUE_ID IN (`+placeholders(len(ids))+`)`, ids...)
+            if err != nil {
+                return err
+            }
+            defer rows.Close()
+            for rows.Next() {
+                var id uint
+                var login string
+                if err := rows.Scan(&id, &login); err != nil {
+                    return err
+                }
+                i := index[id]
+                s.issues[i].Assignees = append(s.issues[i].Assignees, login)
+            }
+            return rows.Err()
+        }(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// createdDI returns DI of issues created in [startTime, endTime), with their labels at endTime
+func (s *snapshot) createdDI(startTime, endTime time.Time, model *WeightModel) (float64, *Report) {
+    issues := make([]Issue, 0)
+    for _, issue := range s.issues {
+        if !issue.CreatedAt.Before(startTime) && issue.CreatedAt.Before(endTime) {
+            if issue, ok := s.at(issue, endTime); ok {
+                issues = append(issues, issue)
+            }
+        }
+    }
+    return calculateDI(issues, model)
+}
+
+// closedDI returns DI of issues closed in [startTime, endTime), with their labels at endTime,
+// an issue closed several times in the window is counted each time
+func (s *snapshot) closedDI(startTime, endTime time.Time, model *WeightModel) (float64, *Report) {
+    issues := make([]Issue, 0)
+    for _, issue := range s.issues {
+        for _, interval := range issue.intervals() {
+            if !interval.End.IsZero() && !interval.End.Before(startTime) && interval.End.Before(endTime) {
+                if issue, ok := s.at(issue, endTime); ok {
+                    issues = append(issues, issue)
+                }
+            }
+        }
+    }
+    return calculateDI(issues, model)
+}
+
+// reopenedDI returns DI of issues reopened in [startTime, endTime), with their labels at endTime,
+// an issue reopened several times in the window is counted each time
+func (s *snapshot) reopenedDI(startTime, endTime time.Time, model *WeightModel) (float64, *Report) {
+    issues := make([]Issue, 0)
+    for _, issue := range s.issues {
+        for _, interval := range issue.intervals()[1:] {
+            if !interval.Start.Before(startTime) && interval.Start.Before(endTime) {
+                if issue, ok := s.at(issue, endTime); ok {
+                    issues = append(issues, issue)
+                }
+            }
+        }
+    }
+    return calculateDI(issues, model)
+}
+
+// instantDI returns DI of issues open at time t, which are opened before t and not closed before t, with their labels at t
+func (s *snapshot) instantDI(t time.Time, model *WeightModel) (float64, *Report) {
+    issues := make([]Issue, 0)
+    for _, issue := range s.issues {
+        if openAt(issue, t) {
+            if issue, ok := s.at(issue, t); ok {
+                issues = append(issues, issue)
+            }
+        }
+    }
+    return calculateDI(issues, model)
+}
+
+// openAt returns whether issue is open at t in any of its open intervals
+func openAt(issue Issue, t time.Time) bool {
+    for _, interval := range issue.intervals() {
+        if interval.openAt(t) {
+            return true
+        }
+    }
+    return false
+}
+
+// addLabel adds a label name to the labels of an issue, split by "/"
+func addLabel(issue *Issue, label string) {
+    parts := strings.Split(label, "/")
+    switch len(parts) {
+    case 1:
+        issue.Label[parts[0]] = append(issue.Label[parts[0]], "")
+    case 2:
+        issue.Label[parts[0]] = append(issue.Label[parts[0]], parts[1])
+    default:
+        log.Printf("Issue %v has unsupported label %s", issue.Number, label)
+    }
+}
+
+// nullTime scans a nullable DATETIME whether the DSN parses time or not,
+// DATETIME not parsed by the driver is in UTC as the driver writes time
+type nullTime struct {
+    Time  time.Time
+    Valid bool
+}
+
+// Scan implements the sql.Scanner interface
+func (t *nullTime) Scan(value interface{}) error {
+    var s string
+    switch v := value.(type) {
+    case nil:
+        t.Time, t.Valid = time.Time{}, false
+        return nil
+    case time.Time:
+        t.Time, t.Valid = v, true
+        return nil
+    case []byte:
+        s = string(v)
+    case string:
+        s = v
+    default:
+        return fmt.Errorf("cannot scan %T into time", value)
+    }
+
+    parsed, err := time.ParseInLocation("2006-01-02 15:04:05.999999", s, time.UTC)
+    if err != nil {
+        return err
+    }
+    t.Time, t.Valid = parsed, true
+    return nil
+}
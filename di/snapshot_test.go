@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "testing"
+    "time"
+)
+
+func newTestSnapshot() *snapshot {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    issue := func(number int, severity string, createdAt, closedAt time.Time) Issue {
+        issue := Issue{Number: number, CreatedAt: createdAt, ClosedAt: closedAt, Closed: !closedAt.IsZero(),
+            Label: make(map[string][]string)}
+        addLabel(&issue, "severity/"+severity)
+        return issue
+    }
+    return &snapshot{issues: []Issue{
+        issue(1, "critical", day(1), day(10)),
+        issue(2, "major", day(3), time.Time{}),
+        issue(3, "minor", day(8), day(9)),
+    }}
+}
+
+func TestSnapshot(t *testing.T) {
+    s := newTestSnapshot()
+    start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+    end := time.Date(2020, 9, 7, 0, 0, 0, 0, time.UTC)
+
+    di, _ := s.createdDI(start, end, &DefaultWeightModel)
+    must(t, di, criticalDI+majorDI, "createdDI")
+    di, _ = s.closedDI(end, end.AddDate(0, 0, 7), &DefaultWeightModel)
+    must(t, di, criticalDI+minorDI, "closedDI")
+    di, _ = s.instantDI(end, &DefaultWeightModel)
+    must(t, di, criticalDI+majorDI, "instantDI")
+    di, _ = s.instantDI(end.AddDate(0, 0, 7), &DefaultWeightModel)
+    must(t, di, majorDI, "instantDI")
+}
+
+func TestNullTimeScan(t *testing.T) {
+    var nt nullTime
+    must(t, nt.Scan([]byte("2020-09-07 12:30:00")), nil, "err")
+    must(t, nt.Valid, true, "nt.Valid")
+    must(t, nt.Time.Equal(time.Date(2020, 9, 7, 12, 30, 0, 0, time.UTC)), true, "nt.Time.Equal")
+    must(t, nt.Scan(nil), nil, "err")
+    must(t, nt.Valid, false, "nt.Valid")
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "time"
+
+    "github.com/PingCAP-QE/libs/crawler"
+)
+
+// IssueSource define where the issues DI is calculated on are loaded from
+type IssueSource interface {
+    // Issues returns the issues matched by filter which are created before endTime and not closed before startTime,
+    // with their labels and open intervals, with their label events in chronological order if history is true,
+    // and with their assignees if assignees is true, a source may return the assignees even if it is false.
+    // Only the repository condition of filter is matched if history is true,
+    // as the label conditions are matched by the labels of issues at each time.
+    Issues(filter Filter, startTime, endTime time.Time, history, assignees bool) ([]Issue, error)
+}
+
+// MemorySource define issues held in memory, e.g. converted from a crawl
+type MemorySource struct {
+    issues []Issue
+}
+
+// NewMemorySource returns a MemorySource of issues,
+// issues without open intervals are open from CreatedAt to ClosedAt
+func NewMemorySource(issues []Issue) *MemorySource {
+    return &MemorySource{issues: issues}
+}
+
+// NewCrawlerSource returns a MemorySource of issues fetched by crawler,
+// their labels and timeline items must be fetched completely
+func NewCrawlerSource(issues []crawler.IssueWithComments) *MemorySource {
+    converted := make([]Issue, 0, len(issues))
+    for _, issue := range issues {
+        converted = append(converted, issueOf(issue.Issue))
+    }
+    return NewMemorySource(converted)
+}
+
+// NewJSONSource returns a MemorySource of issues fetched by crawler and saved in a JSON file,
+// which is an array of crawler.IssueWithComments
+func NewJSONSource(path string) (*MemorySource, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var issues []crawler.IssueWithComments
+    if err := json.Unmarshal(data, &issues); err != nil {
+        return nil, err
+    }
+    return NewCrawlerSource(issues), nil
+}
+
+// Issues implements the IssueSource interface, the issues are returned with their assignees
+func (s *MemorySource) Issues(filter Filter, startTime, endTime time.Time, history, assignees bool) ([]Issue, error) {
+    issues := make([]Issue, 0)
+    for _, issue := range s.issues {
+        if !issue.CreatedAt.Before(endTime) || (issue.Closed && issue.ClosedAt.Before(startTime)) {
+            continue
+        }
+        if len(filter.Repos) > 0 && !containsAny([]string{issue.RepoName}, filter.Repos) {
+            continue
+        }
+        if !history && !filter.matchLabels(issue.LabelNames) {
+            continue
+        }
+        issues = append(issues, issue)
+    }
+    return issues, nil
+}
+
+// issueOf returns an Issue converted from an issue fetched by crawler,
+// with its label events and open intervals built by its timeline
+func issueOf(issue crawler.Issue) Issue {
+    converted := Issue{
+        ID:        uint(issue.DatabaseId),
+        Number:    int(issue.Number),
+        RepoName:  string(issue.Repository.Name),
+        Closed:    bool(issue.Closed),
+        ClosedAt:  issue.ClosedAt.Time,
+        CreatedAt: issue.CreatedAt.Time,
+        Title:     string(issue.Title),
+        Label:     make(map[string][]string),
+    }
+    for _, label := range issue.Labels.Nodes {
+        converted.LabelNames = append(converted.LabelNames, string(label.Name))
+        addLabel(&converted, string(label.Name))
+    }
+    for _, assignee := range issue.Assignees.Nodes {
+        converted.Assignees = append(converted.Assignees, string(assignee.Login))
+    }
+
+    // the timeline is in chronological order.
+    states := make([]stateEvent, 0)
+    for _, item := range issue.TimelineItems.Nodes {
+        switch item.Typename {
+        case crawler.LabeledEventType:
+            converted.LabelEvents = append(converted.LabelEvents,
+                LabelEvent{Time: item.CreatedAt(), Name: string(item.LabeledEvent.Label.Name), Added: true})
+        case crawler.UnlabeledEventType:
+            converted.LabelEvents = append(converted.LabelEvents,
+                LabelEvent{Time: item.CreatedAt(), Name: string(item.UnlabeledEvent.Label.Name)})
+        case crawler.ClosedEventType, crawler.ReopenedEventType:
+            states = append(states, stateEvent{time: item.CreatedAt(), closed: item.Typename == crawler.ClosedEventType})
+        }
+    }
+    converted.Intervals = buildIntervals(converted, states)
+    return converted
+}
@@ -14,243 +14,150 @@
 package di
 
 import (
-    "context"
     "database/sql"
-    "errors"
     "fmt"
-    "log"
-    "strings"
     "time"
 )
 
-// getLabels returns all labels of an issue, saved in map.
-func getLabels(db *sql.DB, issue Issue) (map[string][]string, error) {
-
-    if db == nil {
-        return nil, errors.New("db is nil")
-    }
-
-    labels := make(map[string][]string)
+// SQLSource define issues stored in the MySQL tables written by package storage.
+// The close and reopen events and the label events of issues are read from table ISSUE_EVENT,
+// which is created by migration version 5 and written by storage.StoreIssues since then.
+// Issues without close and reopen events, e.g. stored before ISSUE_EVENT or in a database without it,
+// are open from their creation until they are closed, so they are never counted as reopened.
+type SQLSource struct {
+    db *sql.DB
+}
 
-    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
-    defer cancel()
-    rows, err := db.QueryContext(ctx, `SELECT NAME 
-                                            FROM LABEL_ISSUE_RELATIONSHIP, LABEL 
-                                        	WHERE LABEL_ISSUE_RELATIONSHIP.ISSUE_ID = ? 
-                                            AND LABEL_ISSUE_RELATIONSHIP.LABEL_ID = LABEL.ID`, issue.ID)
+// NewSQLSource returns a SQLSource of issues stored in db
+func NewSQLSource(db *sql.DB) *SQLSource {
+    return &SQLSource{db: db}
+}
 
+// Issues implements the IssueSource interface, the issues are loaded with their labels in a single query,
+// and then their events in batches, and their assignees in batches only if assignees is true
+func (s *SQLSource) Issues(filter Filter, startTime, endTime time.Time, history, assignees bool) ([]Issue, error) {
+    snapshot, err := loadSnapshot(s.db, "CREATED_AT < ? AND (CLOSED = 0 OR CLOSED_AT >= ?)", filter, history, endTime, startTime)
     if err != nil {
         return nil, err
     }
-
-    for rows.Next() {
-        var label string
-        err := rows.Scan(&label)
-
-        if err != nil {
+    if assignees {
+        if err := snapshot.loadAssignees(s.db); err != nil {
             return nil, err
         }
-        parts := strings.Split(label, "/")
-        switch len(parts) {
-        case 1:
-            labels[parts[0]] = append(labels[parts[0]], "")
-        case 2:
-            labels[parts[0]] = append(labels[parts[0]], parts[1])
-        default:
-            log.Printf("Issue %v has unsupported label %s", issue.Number, label)
-        }
-    }
-
-    return labels, nil
-}
-
-// generateQuery generates query from original query string with repo and sig
-// only non-empty repo and sig will be involved
-func generateQuery(query, repo, sig string) string {
-    if len(repo) > 0 {
-        query += ` AND REPOSITORY_ID = (
-                    SELECT ID
-                    FROM REPOSITORY
-                    WHERE REPO_NAME = '` + repo + `')`
-    }
-    if len(sig) > 0 {
-        query += ` AND ID IN (
-                    SELECT ISSUE_ID
-                    FROM LABEL_ISSUE_RELATIONSHIP
-                        LEFT JOIN LABEL ON LABEL_ISSUE_RELATIONSHIP.LABEL_ID = LABEL.ID
-                    WHERE LABEL.NAME = '` + sig + `')`
     }
-    return query
+    return snapshot.issues, nil
 }
 
-// getClosedDI returns DI of issues created between startTime and endTime
-// only non-empty repo and sig will be involved
-func getCreatedDI(db *sql.DB, repo, sig string, startTime, endTime time.Time) (float64, error) {
-    if db == nil {
-        return 0, errors.New("db is nil")
-    }
-
-    if startTime.After(endTime) {
-        return 0, errors.New("startTime > endTime")
-    }
-
-    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
-    defer cancel()
-
-    query := generateQuery("SELECT ID, NUMBER FROM ISSUE WHERE CREATED_AT BETWEEN ? AND ?", repo, sig)
-    rows, err := db.QueryContext(ctx, query, startTime, endTime)
-
-    if err != nil {
-        return 0, err
-    }
-
-    issues, err := parseIssues(rows)
-    if err != nil {
-        return 0, err
-    }
-
-    for i, _ := range issues {
-        issues[i].Label, err = getLabels(db, issues[i])
-        if err != nil {
-            return 0, err
-        }
+// insertIntervalDI upserts an IntervalDI into table (not committed)
+func insertIntervalDI(tx *sql.Tx, table string, repo, sig string, di IntervalDI) error {
+    if err := checkTable(table); err != nil {
+        return err
     }
-
-    di := calculateDI(issues)
-
-    return di, nil
+    _, err := tx.Exec(`INSERT INTO `+table+`(REPO, SIG, START_TIME, END_TIME, DI) VALUES(?, ?, ?, ?, ?)
+                        ON DUPLICATE KEY UPDATE DI = VALUES(DI)`, repo, sig, di.StartTime, di.EndTime, di.Value)
+    return err
 }
 
-// getClosedDI returns DI of issues closed between startTime and endTime
-// only non-empty repo and sig will be involved
-func getClosedDI(db *sql.DB, repo, sig string, startTime, endTime time.Time) (float64, error) {
-    if db == nil {
-        return 0, errors.New("db is nil")
-    }
-
-    if startTime.After(endTime) {
-        return 0, errors.New("startTime > endTime")
-    }
-
-    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
-    defer cancel()
-
-    query := generateQuery("SELECT ID, NUMBER FROM ISSUE WHERE CLOSED_AT BETWEEN ? AND ?", repo, sig)
-    rows, err := db.QueryContext(ctx, query, startTime, endTime)
-
-    if err != nil {
-        return 0, err
-    }
-
-    issues, err := parseIssues(rows)
-    if err != nil {
-        return 0, err
-    }
-
-    for i, _ := range issues {
-        issues[i].Label, err = getLabels(db, issues[i])
-        if err != nil {
-            return 0, err
-        }
+// insertInstantDI upserts an InstantDI into table (not committed)
+func insertInstantDI(tx *sql.Tx, table string, repo, sig string, di InstantDI) error {
+    if err := checkTable(table); err != nil {
+        return err
     }
-
-    di := calculateDI(issues)
-
-    return di, nil
+    _, err := tx.Exec(`INSERT INTO `+table+`(REPO, SIG, TIME, DI) VALUES(?, ?, ?, ?)
+                        ON DUPLICATE KEY UPDATE DI = VALUES(DI)`, repo, sig, di.Time, di.Value)
+    return err
 }
 
-// getDI returns DI at a specified time
-// only non-empty repo and sig will be involved
-func getDI(db *sql.DB, repo, sig string, time time.Time) (float64, error) {
-    if db == nil {
-        return 0, errors.New("db is nil")
-    }
-
-    ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
-    defer cancel()
-
-    query := generateQuery("SELECT ID, NUMBER FROM ISSUE WHERE CREATED_AT < ? AND (CLOSED = 0 OR CLOSED_AT > ?)", repo, sig)
-
-    rows, err := db.QueryContext(ctx, query, time, time)
-    if err != nil {
-        return 0, err
-    }
-
-    issues, err := parseIssues(rows)
-    if err != nil {
-        return 0, err
-    }
-
-    for i, _ := range issues {
-        issues[i].Label, err = getLabels(db, issues[i])
-        if err != nil {
-            return 0, err
-        }
+// deleteIntervalDI deletes the IntervalDIs overlapping [startTime, endTime) from table (not committed)
+func deleteIntervalDI(tx *sql.Tx, table string, repo, sig string, startTime, endTime time.Time) error {
+    if err := checkTable(table); err != nil {
+        return err
     }
-
-    di := calculateDI(issues)
-
-    return di, nil
-}
-
-// insertIntervalDI inserts an IntervalDI into table (not committed)
-func insertIntervalDI(tx *sql.Tx, table string, repo, sig string, di IntervalDI) error {
-    _, err := tx.Exec(`INSERT INTO `+table+`(REPO, SIG, START_TIME, END_TIME, DI) VALUES(?, ?, ?, ?, ?)`, repo, sig, di.StartTime, di.EndTime, di.Value)
+    _, err := tx.Exec(`DELETE FROM `+table+` WHERE REPO = ? AND SIG = ? AND START_TIME < ? AND END_TIME > ?`,
+        repo, sig, endTime, startTime)
     return err
 }
 
-// insertIntervalDI inserts an InstantDI into table (not committed)
-func insertInstantDI(tx *sql.Tx, table string, repo, sig string, di InstantDI) error {
-    _, err := tx.Exec(`INSERT INTO `+table+`(REPO, SIG, TIME, DI) VALUES(?, ?, ?, ?)`, repo, sig, di.Time, di.Value)
+// deleteInstantDI deletes the InstantDIs between startTime and endTime from table (not committed)
+func deleteInstantDI(tx *sql.Tx, table string, repo, sig string, startTime, endTime time.Time) error {
+    if err := checkTable(table); err != nil {
+        return err
+    }
+    _, err := tx.Exec(`DELETE FROM `+table+` WHERE REPO = ? AND SIG = ? AND TIME BETWEEN ? AND ?`,
+        repo, sig, startTime, endTime)
     return err
 }
 
-// storeIntervalDI inserts an array of IntervalDI into table and commits
-func storeIntervalDI(db *sql.DB, table string, repo, sig string, dis []IntervalDI) error {
+// storeIntervalDI upserts an array of IntervalDI into table and commits,
+// the stored IntervalDIs overlapping the range of dis are deleted first if replaceRange is true
+func storeIntervalDI(db *sql.DB, table string, repo, sig string, dis []IntervalDI, replaceRange bool) (err error) {
     tx, err := db.Begin()
     if err != nil {
         return err
     }
-    fmt.Println("interval di txn begin")
     defer func(){
         if err != nil {
-            fmt.Println("interval di txn rollback")
-            if err1 := tx.Rollback(); err1 != nil {
-                panic(err1)
+            // the transaction is done if it fails to commit.
+            if err1 := tx.Rollback(); err1 != nil && err1 != sql.ErrTxDone {
+                err = fmt.Errorf("%w, and rollback failed: %v", err, err1)
             }
         }
     }()
+    if replaceRange && len(dis) > 0 {
+        startTime, endTime := dis[0].StartTime, dis[0].EndTime
+        for _, di := range dis {
+            if di.StartTime.Before(startTime) {
+                startTime = di.StartTime
+            }
+            if di.EndTime.After(endTime) {
+                endTime = di.EndTime
+            }
+        }
+        if err = deleteIntervalDI(tx, table, repo, sig, startTime, endTime); err != nil {
+            return err
+        }
+    }
     for _, di := range dis {
         if err = insertIntervalDI(tx, table, repo, sig, di); err != nil {
             return err
         }
     }
-    tx.Commit()
-    fmt.Println("interval di txn commit")
-    return nil
+    return tx.Commit()
 }
 
-// storeInstantDI inserts an array of InstantDI into table and commits
-func storeInstantDI(db *sql.DB, table string, repo, sig string, dis []InstantDI) error {
+// storeInstantDI upserts an array of InstantDI into table and commits,
+// the stored InstantDIs within the range of dis are deleted first if replaceRange is true
+func storeInstantDI(db *sql.DB, table string, repo, sig string, dis []InstantDI, replaceRange bool) (err error) {
     tx, err := db.Begin()
     if err != nil {
         return err
     }
-    fmt.Println("instant di txn begin")
     defer func(){
         if err != nil {
-            fmt.Println("instant di txn rollback")
-            if err1 := tx.Rollback(); err1 != nil {
-                panic(err1)
+            // the transaction is done if it fails to commit.
+            if err1 := tx.Rollback(); err1 != nil && err1 != sql.ErrTxDone {
+                err = fmt.Errorf("%w, and rollback failed: %v", err, err1)
             }
         }
     }()
+    if replaceRange && len(dis) > 0 {
+        startTime, endTime := dis[0].Time, dis[0].Time
+        for _, di := range dis {
+            if di.Time.Before(startTime) {
+                startTime = di.Time
+            }
+            if di.Time.After(endTime) {
+                endTime = di.Time
+            }
+        }
+        if err = deleteInstantDI(tx, table, repo, sig, startTime, endTime); err != nil {
+            return err
+        }
+    }
     for _, di := range dis {
         if err = insertInstantDI(tx, table, repo, sig, di); err != nil {
             return err
         }
     }
-    tx.Commit()
-    fmt.Println("instant di txn commit")
-    return nil
+    return tx.Commit()
 }
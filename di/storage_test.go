@@ -19,6 +19,9 @@ import (
     "os"
     "testing"
     "time"
+
+    "github.com/PingCAP-QE/libs/internal/testdb"
+    "github.com/PingCAP-QE/libs/migration"
 )
 
 var diDB *sql.DB
@@ -31,6 +34,11 @@ func init() {
         log.Fatal(err)
     }
     diDB.SetConnMaxLifetime(10 * time.Minute)
+    if dsn != "" {
+        if err := migration.EnsureSchema(diDB); err != nil {
+            log.Fatal(err)
+        }
+    }
 }
 
 func clearDB(db *sql.DB) {
@@ -47,6 +55,9 @@ func clearDB(db *sql.DB) {
     if _, err := tx.Exec(`DELETE FROM CLOSED_DI WHERE REPO = 'test'`); err != nil {
         log.Fatal(err)
     }
+    if _, err := tx.Exec(`DELETE FROM REOPENED_DI WHERE REPO = 'test'`); err != nil {
+        log.Fatal(err)
+    }
     tx.Commit()
 }
 
@@ -78,7 +89,7 @@ func TestInsertInstantDI(t *testing.T) {
 func TestStoreIntervalDI(t *testing.T) {
     dis := []IntervalDI{{time.Now(), time.Now().AddDate(0, 0, -7), 10},
         {time.Now().AddDate(0, 0, -7), time.Now().AddDate(0, 0, -14), 20}}
-    err := storeIntervalDI(diDB, "CREATED_DI", "test", "sig/test", dis)
+    err := storeIntervalDI(diDB, "CREATED_DI", "test", "sig/test", dis, false)
     must(t, err, nil, "err")
     clearDB(diDB)
 }
@@ -86,15 +97,44 @@ func TestStoreIntervalDI(t *testing.T) {
 func TestStoreInstantDI(t *testing.T) {
     dis := []InstantDI{{time.Now(), 10},
         {time.Now().AddDate(0, 0, -7), 20}}
-    err := storeInstantDI(diDB, "DI", "test", "sig/test", dis)
+    err := storeInstantDI(diDB, "DI", "test", "sig/test", dis, false)
     must(t, err, nil, "err")
     clearDB(diDB)
 }
 
+func TestStoreIntervalDIRerun(t *testing.T) {
+    db := testdb.Open(t)
+    must(t, migration.EnsureSchema(db), nil, "err")
+    startTime := time.Date(2020, 9, 7, 0, 0, 0, 0, time.UTC)
+    dis := []IntervalDI{{startTime, startTime.AddDate(0, 0, 7), 10},
+        {startTime.AddDate(0, 0, 7), startTime.AddDate(0, 0, 14), 20}}
+    err := storeIntervalDI(db, "CREATED_DI", "test", "sig/test", dis, false)
+    must(t, err, nil, "err")
+
+    // a rerun upserts the overlapping window.
+    err = storeIntervalDI(db, "CREATED_DI", "test", "sig/test", []IntervalDI{{startTime, startTime.AddDate(0, 0, 7), 30}}, false)
+    must(t, err, nil, "err")
+    var count int
+    var di float64
+    err = db.QueryRow(`SELECT COUNT(*), SUM(DI) FROM CREATED_DI WHERE REPO = 'test'`).Scan(&count, &di)
+    must(t, err, nil, "err")
+    must(t, count, 2, "count")
+    must(t, di, 50.0, "di")
+
+    // a rerun replacing the range deletes the other windows in it.
+    err = storeIntervalDI(db, "CREATED_DI", "test", "sig/test", []IntervalDI{{startTime, startTime.AddDate(0, 0, 14), 40}}, true)
+    must(t, err, nil, "err")
+    err = db.QueryRow(`SELECT COUNT(*), SUM(DI) FROM CREATED_DI WHERE REPO = 'test'`).Scan(&count, &di)
+    must(t, err, nil, "err")
+    must(t, count, 1, "count")
+    must(t, di, 40.0, "di")
+    clearDB(db)
+}
+
 func TestGetCreatedDi(t *testing.T) {
     startTime := time.Date(2020, 9, 7, 0, 0, 0, 0, time.UTC)
     endTime := time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC)
-    di, err := getCreatedDI(issueDB, "tidb", "sig/execution", startTime, endTime)
+    di, _, err := NewEngine(NewSQLSource(issueDB)).CreatedDI("tidb", "sig/execution", startTime, endTime)
     must(t, err, nil, "err")
     must(t, di, 3.0, "di")
 }
@@ -102,7 +142,7 @@ func TestGetCreatedDi(t *testing.T) {
 func TestGetClosedDI(t *testing.T) {
     startTime := time.Date(2020, 9, 7, 0, 0, 0, 0, time.UTC)
     endTime := time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC)
-    di, err := getClosedDI(issueDB, "tidb", "", startTime, endTime)
+    di, _, err := NewEngine(NewSQLSource(issueDB)).ClosedDI("tidb", "", startTime, endTime)
     must(t, err, nil, "err")
     must(t, di, 107.0, "di")
 }
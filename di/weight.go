@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// Errors of issues which cannot be weighted by a WeightModel
+var (
+    ErrNoSeverity         = errors.New("no severity")
+    ErrMultipleSeverities = errors.New("multiple severities")
+    ErrUnknownSeverity    = errors.New("unsupported severity")
+)
+
+// MultipleRule define how an issue with multiple severities is weighted
+type MultipleRule int
+
+const (
+    // RejectMultiple skips issues with multiple severities
+    RejectMultiple MultipleRule = iota
+    // MaxMultiple weights issues by their most severe severity
+    MaxMultiple
+    // SumMultiple weights issues by the sum of all their severities
+    SumMultiple
+)
+
+// WeightModel define the DI of an issue by its severity labels
+type WeightModel struct {
+    // Prefix is the prefix of severity labels, e.g. "severity" for labels like "severity/critical".
+    // If Prefix is empty, severities are labels without prefix, e.g. "P0".
+    Prefix string
+    // Weights are the DI of each severity
+    Weights map[string]float64
+    // Fallback is the DI of issues without severity, these issues are skipped if Fallback is nil
+    Fallback *float64
+    // Multiple is the rule for issues with multiple severities
+    Multiple MultipleRule
+}
+
+// DefaultWeightModel weights issues by labels severity/critical, severity/major, severity/moderate and severity/minor
+var DefaultWeightModel = WeightModel{
+    Prefix: "severity",
+    Weights: map[string]float64{
+        "critical": criticalDI,
+        "major":    majorDI,
+        "moderate": moderateDI,
+        "minor":    minorDI,
+    },
+    Multiple: RejectMultiple,
+}
+
+// severities returns the severities in labels
+func (m *WeightModel) severities(labels map[string][]string) []string {
+    if m.Prefix != "" {
+        return labels[m.Prefix]
+    }
+    severities := make([]string, 0)
+    for name, values := range labels {
+        for _, value := range values {
+            if value == "" {
+                severities = append(severities, name)
+            }
+        }
+    }
+    sort.Strings(severities)
+    return severities
+}
+
+// Weight returns DI of an issue with labels,
+// which are label names split by "/" as loaded into Issue.Label
+func (m *WeightModel) Weight(labels map[string][]string) (float64, error) {
+    di, _, err := m.weigh(labels)
+    return di, err
+}
+
+// weigh returns DI of an issue with labels and the severities weighted,
+// which is empty if the issue is weighted by Fallback
+func (m *WeightModel) weigh(labels map[string][]string) (float64, []string, error) {
+    severities := m.severities(labels)
+    if m.Prefix == "" {
+        // labels without prefix are severities only if they are weighted.
+        known := severities[:0]
+        for _, severity := range severities {
+            if _, ok := m.Weights[severity]; ok {
+                known = append(known, severity)
+            }
+        }
+        severities = known
+    }
+
+    if len(severities) == 0 {
+        if m.Fallback != nil {
+            return *m.Fallback, nil, nil
+        }
+        return 0, nil, ErrNoSeverity
+    }
+    if len(severities) > 1 && m.Multiple == RejectMultiple {
+        return 0, nil, ErrMultipleSeverities
+    }
+
+    di := 0.0
+    weighted := severities[:1]
+    for i, severity := range severities {
+        weight, ok := m.Weights[severity]
+        if !ok {
+            return 0, nil, fmt.Errorf("%w %s", ErrUnknownSeverity, severity)
+        }
+        switch {
+        case m.Multiple == SumMultiple:
+            di += weight
+            weighted = severities
+        case i == 0 || weight > di:
+            di = weight
+            weighted = severities[i : i+1]
+        }
+    }
+    return di, weighted, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "errors"
+    "sort"
+    "time"
+)
+
+// Window define a period DI is calculated of, which includes its start time and excludes its end time
+type Window struct {
+    Start time.Time
+    End   time.Time
+}
+
+// windowUnit define how a WindowSpec steps from a window to the next one
+type windowUnit int
+
+const (
+    fixedUnit windowUnit = iota
+    dayUnit
+    weekUnit
+    monthUnit
+    quarterUnit
+    boundaryUnit
+)
+
+// Partial define how the last window is handled if it does not end at the end time
+type Partial int
+
+const (
+    // PartialExtend keeps the last window full, so it ends after the end time
+    PartialExtend Partial = iota
+    // PartialTruncate ends the last window at the end time
+    PartialTruncate
+    // PartialDrop drops the last window, so the windows end at or before the end time
+    PartialDrop
+)
+
+// WindowSpec define consecutive windows DI series are calculated of.
+// Windows of Every start at the start time, and the other windows are aligned to the calendar of their location,
+// and to multiples of their length since the Unix epoch,
+// so the first window is the one containing the start time, which may start before it.
+// The windows cover the start time until the end time, the last window is handled by the Partial of the spec.
+type WindowSpec struct {
+    unit       windowUnit
+    n          int
+    duration   time.Duration
+    boundaries []time.Time
+    location   *time.Location
+    partial    Partial
+}
+
+// Every returns the spec of windows of duration d starting at the start time
+func Every(d time.Duration) WindowSpec {
+    return WindowSpec{unit: fixedUnit, duration: d}
+}
+
+// Days returns the spec of windows of n days starting at midnight
+func Days(n int) WindowSpec {
+    return WindowSpec{unit: dayUnit, n: n}
+}
+
+// ISOWeeks returns the spec of windows of n ISO weeks starting at midnight on Monday
+func ISOWeeks(n int) WindowSpec {
+    return WindowSpec{unit: weekUnit, n: n}
+}
+
+// Months returns the spec of windows of n calendar months starting at midnight on the first day of a month
+func Months(n int) WindowSpec {
+    return WindowSpec{unit: monthUnit, n: n}
+}
+
+// Quarters returns the spec of windows of n quarters starting at midnight on January, April, July or October 1st
+func Quarters(n int) WindowSpec {
+    return WindowSpec{unit: quarterUnit, n: n}
+}
+
+// Releases returns the spec of windows between consecutive boundaries, e.g. the release dates of a release cycle.
+// The first window is the one containing the start time, or the one starting at the first boundary after it,
+// and the window after the last boundary ends at the end time unless the Partial of the spec is PartialDrop.
+func Releases(boundaries ...time.Time) WindowSpec {
+    sorted := append([]time.Time(nil), boundaries...)
+    sort.Slice(sorted, func(i, j int) bool {
+        return sorted[i].Before(sorted[j])
+    })
+    return WindowSpec{unit: boundaryUnit, boundaries: sorted}
+}
+
+// In returns the spec with windows aligned to the calendar of loc instead of the location of the start time
+func (spec WindowSpec) In(loc *time.Location) WindowSpec {
+    spec.location = loc
+    return spec
+}
+
+// WithPartial returns the spec with the last window handled by partial instead of PartialExtend
+func (spec WindowSpec) WithPartial(partial Partial) WindowSpec {
+    spec.partial = partial
+    return spec
+}
+
+// Windows returns the consecutive windows of the spec covering startTime until endTime
+func (spec WindowSpec) Windows(startTime, endTime time.Time) ([]Window, error) {
+    if startTime.After(endTime) {
+        return nil, errors.New("startTime > endTime")
+    }
+    switch spec.unit {
+    case fixedUnit:
+        if spec.duration <= 0 {
+            return nil, errors.New("frequency <= 0")
+        }
+    case boundaryUnit:
+        if len(spec.boundaries) == 0 {
+            return nil, errors.New("no boundaries")
+        }
+        return spec.releaseWindows(startTime, endTime), nil
+    default:
+        if spec.n <= 0 {
+            return nil, errors.New("n <= 0")
+        }
+    }
+
+    loc := spec.location
+    if loc == nil {
+        loc = startTime.Location()
+    }
+    windows := make([]Window, 0)
+    for start := spec.align(startTime.In(loc)); start.Before(endTime); {
+        window := Window{Start: start, End: spec.next(start)}
+        if !spec.fit(&window, endTime) {
+            break
+        }
+        windows = append(windows, window)
+        start = window.End
+    }
+    return windows, nil
+}
+
+// releaseWindows returns the windows between the boundaries covering startTime until endTime
+func (spec WindowSpec) releaseWindows(startTime, endTime time.Time) []Window {
+    windows := make([]Window, 0)
+    for i, boundary := range spec.boundaries {
+        if !boundary.Before(endTime) {
+            break
+        }
+        window := Window{Start: boundary, End: endTime}
+        if i+1 < len(spec.boundaries) {
+            window.End = spec.boundaries[i+1]
+        } else if spec.partial == PartialDrop {
+            break
+        }
+        if !window.End.After(startTime) {
+            continue
+        }
+        if !spec.fit(&window, endTime) {
+            break
+        }
+        windows = append(windows, window)
+    }
+    return windows
+}
+
+// fit handles a window ending after endTime by the Partial of the spec,
+// and returns false if the window is dropped
+func (spec WindowSpec) fit(window *Window, endTime time.Time) bool {
+    if !window.End.After(endTime) {
+        return true
+    }
+    switch spec.partial {
+    case PartialTruncate:
+        window.End = endTime
+    case PartialDrop:
+        return false
+    }
+    return true
+}
+
+// align returns the start of the window containing t, windows of n units are aligned to multiples of n units
+// since the Unix epoch, e.g. windows of Quarters(2) are half years, and windows of ISOWeeks(2) start on 1970-01-05
+func (spec WindowSpec) align(t time.Time) time.Time {
+    year, month, day := t.Date()
+    switch spec.unit {
+    case dayUnit:
+        return time.Date(year, month, day-floorMod(daysSinceEpoch(year, month, day), spec.n), 0, 0, 0, 0, t.Location())
+    case weekUnit:
+        // 1970-01-05 is the first Monday since the epoch, and Monday is the first day of an ISO week.
+        return time.Date(year, month, day-floorMod(daysSinceEpoch(year, month, day)-4, 7*spec.n), 0, 0, 0, 0, t.Location())
+    case monthUnit:
+        months := (year-1970)*12 + int(month) - 1
+        return time.Date(year, month-time.Month(floorMod(months, spec.n)), 1, 0, 0, 0, 0, t.Location())
+    case quarterUnit:
+        months := (year-1970)*12 + int(month) - 1
+        return time.Date(year, month-time.Month(floorMod(months, 3*spec.n)), 1, 0, 0, 0, 0, t.Location())
+    }
+    return t
+}
+
+// daysSinceEpoch returns the number of calendar days from 1970-01-01 to the date
+func daysSinceEpoch(year int, month time.Month, day int) int {
+    return int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// floorMod returns x modulo n, which is not negative for a positive n
+func floorMod(x, n int) int {
+    return ((x % n) + n) % n
+}
+
+// next returns the start of the window after the one starting at start
+func (spec WindowSpec) next(start time.Time) time.Time {
+    switch spec.unit {
+    case dayUnit:
+        return start.AddDate(0, 0, spec.n)
+    case weekUnit:
+        return start.AddDate(0, 0, 7*spec.n)
+    case monthUnit:
+        return start.AddDate(0, spec.n, 0)
+    case quarterUnit:
+        return start.AddDate(0, 3*spec.n, 0)
+    }
+    return start.Add(spec.duration)
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package di
+
+import (
+    "fmt"
+    "math"
+    "testing"
+    "time"
+)
+
+// formatWindows returns the windows formatted as "start~end" in the layout
+func formatWindows(windows []Window, layout string) string {
+    formatted := make([]string, 0, len(windows))
+    for _, window := range windows {
+        formatted = append(formatted, window.Start.Format(layout)+"~"+window.End.Format(layout))
+    }
+    return fmt.Sprint(formatted)
+}
+
+func TestWindowSpec(t *testing.T) {
+    date := func(year int, month time.Month, day int) time.Time { return time.Date(year, month, day, 12, 0, 0, 0, time.UTC) }
+    cases := []struct {
+        spec      WindowSpec
+        startTime time.Time
+        endTime   time.Time
+        expected  string
+    }{
+        {Every(7 * 24 * time.Hour), date(2020, 9, 2), date(2020, 9, 12),
+            "[2020-09-02~2020-09-09 2020-09-09~2020-09-16]"},
+        {Every(7 * 24 * time.Hour).WithPartial(PartialTruncate), date(2020, 9, 2), date(2020, 9, 12),
+            "[2020-09-02~2020-09-09 2020-09-09~2020-09-12]"},
+        {Every(7 * 24 * time.Hour).WithPartial(PartialDrop), date(2020, 9, 2), date(2020, 9, 12),
+            "[2020-09-02~2020-09-09]"},
+        // 2020-09-02 is a Wednesday.
+        {ISOWeeks(1), date(2020, 9, 2), date(2020, 9, 14),
+            "[2020-08-31~2020-09-07 2020-09-07~2020-09-14 2020-09-14~2020-09-21]"},
+        {Months(1), date(2020, 1, 31), date(2020, 3, 1),
+            "[2020-01-01~2020-02-01 2020-02-01~2020-03-01 2020-03-01~2020-04-01]"},
+        {Months(1).WithPartial(PartialDrop), date(2020, 1, 31), date(2020, 3, 1),
+            "[2020-01-01~2020-02-01 2020-02-01~2020-03-01]"},
+        {Quarters(1), date(2020, 5, 20), date(2020, 10, 1),
+            "[2020-04-01~2020-07-01 2020-07-01~2020-10-01 2020-10-01~2021-01-01]"},
+        // 2020-02-26 is 18318 days, a multiple of 2 days, since 1970-01-01.
+        {Days(2), date(2020, 2, 27), date(2020, 3, 2),
+            "[2020-02-26~2020-02-28 2020-02-28~2020-03-01 2020-03-01~2020-03-03]"},
+        {ISOWeeks(2), date(2020, 9, 2), date(2020, 9, 14),
+            "[2020-08-24~2020-09-07 2020-09-07~2020-09-21]"},
+        {Months(6), date(2020, 9, 2), date(2021, 2, 1),
+            "[2020-07-01~2021-01-01 2021-01-01~2021-07-01]"},
+        {Quarters(2), date(2020, 5, 20), date(2020, 10, 1),
+            "[2020-01-01~2020-07-01 2020-07-01~2021-01-01]"},
+        {Releases(date(2020, 6, 1), date(2020, 3, 1), date(2020, 9, 1)), date(2020, 4, 1), date(2020, 10, 1),
+            "[2020-03-01~2020-06-01 2020-06-01~2020-09-01 2020-09-01~2020-10-01]"},
+        {Releases(date(2020, 3, 1), date(2020, 6, 1), date(2020, 9, 1)).WithPartial(PartialDrop), date(2020, 1, 1), date(2020, 10, 1),
+            "[2020-03-01~2020-06-01 2020-06-01~2020-09-01]"},
+    }
+    for i, c := range cases {
+        windows, err := c.spec.Windows(c.startTime, c.endTime)
+        must(t, err, nil, "err")
+        must(t, formatWindows(windows, "2006-01-02"), c.expected, fmt.Sprintf("windows of case %d", i))
+    }
+
+    // monthly windows are aligned to the midnight of the time zone, with the daylight saving time handled.
+    loc := time.FixedZone("UTC+8", 8*60*60)
+    windows, err := Months(1).In(loc).Windows(date(2020, 1, 31).Add(13*time.Hour), date(2020, 2, 10))
+    must(t, err, nil, "err")
+    must(t, formatWindows(windows, time.RFC3339), "[2020-02-01T00:00:00+08:00~2020-03-01T00:00:00+08:00]", "windows in UTC+8")
+    if newYork, err := time.LoadLocation("America/New_York"); err == nil {
+        windows, err = Days(1).In(newYork).Windows(date(2020, 3, 8), date(2020, 3, 9))
+        must(t, err, nil, "err")
+        must(t, windows[0].End.Sub(windows[0].Start), 23*time.Hour, "day of daylight saving time")
+    }
+
+    _, err = Every(0).Windows(date(2020, 9, 2), date(2020, 9, 12))
+    must(t, err != nil, true, "err != nil")
+    _, err = Months(0).Windows(date(2020, 9, 2), date(2020, 9, 12))
+    must(t, err != nil, true, "err != nil")
+    _, err = Releases().Windows(date(2020, 9, 2), date(2020, 9, 12))
+    must(t, err != nil, true, "err != nil")
+}
+
+func TestEngineWindowSeries(t *testing.T) {
+    day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+    engine := NewEngine(newTestSource(t))
+
+    all, err := engine.WindowSeries("", "", day(2), day(10), []WindowSpec{ISOWeeks(1), Every(24 * time.Hour)})
+    must(t, err, nil, "err")
+    must(t, all[0].Frequency, time.Duration(0), "all[0].Frequency")
+    must(t, all[1].Frequency, 24*time.Hour, "all[1].Frequency")
+    // 2020-09-02 is in the ISO week starting on 2020-08-31.
+    must(t, all[0].Instant[0].Time, time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC), "all[0].Instant[0].Time")
+    must(t, len(all[0].Created), 2, "len(all[0].Created)")
+    for _, series := range all {
+        for _, instant := range series.Instant {
+            di, _, err := engine.DI("", "", instant.Time)
+            must(t, err, nil, "err")
+            // the sweep adds the DI in another order.
+            must(t, math.Abs(instant.Value-di) < 1e-9, true, "instant.Value == di")
+        }
+        for _, created := range series.Created {
+            di, _, err := engine.CreatedDI("", "", created.StartTime, created.EndTime)
+            must(t, err, nil, "err")
+            must(t, created.Value, di, "created.Value")
+        }
+    }
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testdb opens the MySQL database the tests of the other packages run against.
+package testdb
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	// register the mysql driver for the tests.
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// DSNEnv is the environment variable of the DSN of the test database.
+const DSNEnv = "MYSQL_DSN"
+
+// Open opens the database of MYSQL_DSN, the test is skipped if it is not set.
+// The database is closed when the test finishes.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Skip(DSNEnv + " is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migration creates and upgrades the tables read and written by this library.
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Migration define a version of the schema.
+// Statements should be idempotent, because MySQL commits DDL implicitly
+// and a migration interrupted before it is recorded is applied again.
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+	// Steps are applied after Statements, for the changes MySQL cannot make idempotent in SQL,
+	// e.g. adding a column, so a step checks the schema before changing it.
+	Steps []func(db *sql.DB) error
+}
+
+const createMigrationTable = `CREATE TABLE IF NOT EXISTS SCHEMA_MIGRATION (
+	VERSION     INT          NOT NULL,
+	DESCRIPTION VARCHAR(255) NOT NULL,
+	APPLIED_AT  DATETIME     NOT NULL,
+	PRIMARY KEY (VERSION)
+)`
+
+// Migrations returns all the migrations of this library in version order.
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// EnsureSchema applies the migrations of this library not applied to db yet.
+func EnsureSchema(db *sql.DB) error {
+	return Migrate(db, migrations)
+}
+
+// Migrate applies the migrations not applied to db yet in version order,
+// the applied versions are recorded in table SCHEMA_MIGRATION.
+func Migrate(db *sql.DB, migrations []Migration) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	if err := validate(migrations); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createMigrationTable); err != nil {
+		return err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		for _, stmt := range m.Statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+			}
+		}
+		for _, step := range m.Steps {
+			if err := step(db); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+			}
+		}
+		if _, err := db.Exec(`INSERT IGNORE INTO SCHEMA_MIGRATION(VERSION, DESCRIPTION, APPLIED_AT) VALUES(?, ?, NOW())`,
+			m.Version, m.Description); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d: %s", m.Version, m.Description)
+	}
+	return nil
+}
+
+// Version returns the latest version applied to db, or 0 if no migration is applied.
+func Version(db *sql.DB) (int, error) {
+	if _, err := db.Exec(createMigrationTable); err != nil {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(VERSION) FROM SCHEMA_MIGRATION`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func appliedVersions(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query(`SELECT VERSION FROM SCHEMA_MIGRATION`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// validate checks the migrations are in strictly increasing order of positive versions.
+func validate(migrations []Migration) error {
+	last := 0
+	for _, m := range migrations {
+		if m.Version <= last {
+			return fmt.Errorf("migration %d is out of order after %d", m.Version, last)
+		}
+		last = m.Version
+	}
+	return nil
+}
+
+// addColumn returns the step adding column to table if the column does not exist.
+func addColumn(table, column, definition string) func(db *sql.DB) error {
+	return func(db *sql.DB) error {
+		found, err := exists(db, `SELECT COUNT(*) FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = DATABASE() AND LOWER(TABLE_NAME) = LOWER(?) AND LOWER(COLUMN_NAME) = LOWER(?)`, table, column)
+		if err != nil || found {
+			return err
+		}
+		_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+		return err
+	}
+}
+
+// addUniqueKey returns the step adding a unique key to table if the key does not exist,
+// the duplicated rows are deleted first, of which the last row scanned is kept.
+// The rows are copied into a table with the key which then replaces table, so writers should be stopped meanwhile.
+func addUniqueKey(table, key string, columns ...string) func(db *sql.DB) error {
+	return func(db *sql.DB) error {
+		dedup, duplicated := table+"_DEDUP", table+"_DUPLICATED"
+		if strings.ToLower(table) == table {
+			dedup, duplicated = table+"_dedup", table+"_duplicated"
+		}
+		// the table replaced by a step interrupted before dropping it.
+		if _, err := db.Exec(`DROP TABLE IF EXISTS ` + duplicated); err != nil {
+			return err
+		}
+		found, err := exists(db, `SELECT COUNT(*) FROM information_schema.STATISTICS
+			WHERE TABLE_SCHEMA = DATABASE() AND LOWER(TABLE_NAME) = LOWER(?) AND LOWER(INDEX_NAME) = LOWER(?)`, table, key)
+		if err != nil || found {
+			return err
+		}
+		for _, stmt := range []string{
+			`DROP TABLE IF EXISTS ` + dedup,
+			`CREATE TABLE ` + dedup + ` LIKE ` + table,
+			`ALTER TABLE ` + dedup + ` ADD UNIQUE KEY ` + key + ` (` + strings.Join(columns, ", ") + `)`,
+			`REPLACE INTO ` + dedup + ` SELECT * FROM ` + table,
+			`RENAME TABLE ` + table + ` TO ` + duplicated + `, ` + dedup + ` TO ` + table,
+			`DROP TABLE ` + duplicated,
+		} {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// exists returns whether the count queried is positive.
+// Names in information_schema are compared in lower case, as they are stored in lower case if lower_case_table_names is set.
+func exists(db *sql.DB, query string, args ...interface{}) (bool, error) {
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
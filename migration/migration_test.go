@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/PingCAP-QE/libs/internal/testdb"
+)
+
+func TestValidate(t *testing.T) {
+	if err := validate(Migrations()); err != nil {
+		t.Error(err)
+	}
+	if err := validate([]Migration{{Version: 2}, {Version: 1}}); err == nil {
+		t.Error("migrations out of order are accepted")
+	}
+	if err := validate([]Migration{{Version: 1}, {Version: 1}}); err == nil {
+		t.Error("migrations of the same version are accepted")
+	}
+}
+
+func TestEnsureSchema(t *testing.T) {
+	db := testdb.Open(t)
+
+	// the second run applies nothing.
+	for i := 0; i < 2; i++ {
+		if err := EnsureSchema(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the steps are applied again after an interrupted migration.
+	for _, m := range migrations {
+		for _, step := range m.Steps {
+			if err := step(db); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	version, err := Version(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := migrations[len(migrations)-1].Version; version != expected {
+		t.Errorf("version = %d; expected %d", version, expected)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import "database/sql"
+
+// migrations of this library, a released migration must never be changed, add a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create issue tables written by storage and read by di",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS REPOSITORY (
+				ID        BIGINT       NOT NULL AUTO_INCREMENT,
+				OWNER     VARCHAR(255) NOT NULL,
+				REPO_NAME VARCHAR(255) NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_OWNER_REPO_NAME (OWNER, REPO_NAME)
+			)`,
+			`CREATE TABLE IF NOT EXISTS ISSUE (
+				ID            BIGINT       NOT NULL,
+				NUMBER        INT          NOT NULL,
+				REPOSITORY_ID BIGINT       NOT NULL,
+				TITLE         TEXT         NOT NULL,
+				BODY          LONGTEXT     NOT NULL,
+				AUTHOR        VARCHAR(255) NOT NULL,
+				CLOSED        TINYINT(1)   NOT NULL,
+				CLOSED_AT     DATETIME     NULL,
+				CREATED_AT    DATETIME     NOT NULL,
+				UPDATED_AT    DATETIME     NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_REPOSITORY_NUMBER (REPOSITORY_ID, NUMBER),
+				KEY IDX_CREATED_AT (CREATED_AT),
+				KEY IDX_CLOSED_AT (CLOSED_AT)
+			)`,
+			`CREATE TABLE IF NOT EXISTS LABEL (
+				ID   BIGINT       NOT NULL AUTO_INCREMENT,
+				NAME VARCHAR(255) NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_NAME (NAME)
+			)`,
+			`CREATE TABLE IF NOT EXISTS LABEL_ISSUE_RELATIONSHIP (
+				ISSUE_ID BIGINT NOT NULL,
+				LABEL_ID BIGINT NOT NULL,
+				PRIMARY KEY (ISSUE_ID, LABEL_ID),
+				KEY IDX_LABEL_ID (LABEL_ID)
+			)`,
+			`CREATE TABLE IF NOT EXISTS ISSUE_ASSIGNEE (
+				ISSUE_ID BIGINT       NOT NULL,
+				LOGIN    VARCHAR(255) NOT NULL,
+				PRIMARY KEY (ISSUE_ID, LOGIN)
+			)`,
+			`CREATE TABLE IF NOT EXISTS COMMENT (
+				ID         BIGINT       NOT NULL,
+				ISSUE_ID   BIGINT       NOT NULL,
+				AUTHOR     VARCHAR(255) NOT NULL,
+				BODY       LONGTEXT     NOT NULL,
+				CREATED_AT DATETIME     NULL,
+				UPDATED_AT DATETIME     NULL,
+				PRIMARY KEY (ID),
+				KEY IDX_ISSUE_ID (ISSUE_ID)
+			)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "create DI tables written by di",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS DI (
+				ID   BIGINT       NOT NULL AUTO_INCREMENT,
+				REPO VARCHAR(255) NOT NULL,
+				SIG  VARCHAR(255) NOT NULL,
+				TIME DATETIME     NOT NULL,
+				DI   DOUBLE       NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_REPO_SIG_TIME (REPO, SIG, TIME)
+			)`,
+			`CREATE TABLE IF NOT EXISTS CREATED_DI (
+				ID         BIGINT       NOT NULL AUTO_INCREMENT,
+				REPO       VARCHAR(255) NOT NULL,
+				SIG        VARCHAR(255) NOT NULL,
+				START_TIME DATETIME     NOT NULL,
+				END_TIME   DATETIME     NOT NULL,
+				DI         DOUBLE       NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_REPO_SIG_WINDOW (REPO, SIG, START_TIME, END_TIME)
+			)`,
+			`CREATE TABLE IF NOT EXISTS CLOSED_DI (
+				ID         BIGINT       NOT NULL AUTO_INCREMENT,
+				REPO       VARCHAR(255) NOT NULL,
+				SIG        VARCHAR(255) NOT NULL,
+				START_TIME DATETIME     NOT NULL,
+				END_TIME   DATETIME     NOT NULL,
+				DI         DOUBLE       NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_REPO_SIG_WINDOW (REPO, SIG, START_TIME, END_TIME)
+			)`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "create coverage_timeline written by coverage",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS coverage_timeline (
+				id       BIGINT   NOT NULL AUTO_INCREMENT,
+				repo_id  BIGINT   NOT NULL,
+				time     DATETIME NOT NULL,
+				coverage DOUBLE   NOT NULL,
+				PRIMARY KEY (id),
+				UNIQUE KEY uk_repo_id_time (repo_id, time)
+			)`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "create DI_BREAKDOWN written by di",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS DI_BREAKDOWN (
+				ID              BIGINT       NOT NULL AUTO_INCREMENT,
+				GROUP_HASH      CHAR(40)     NOT NULL,
+				REPO            VARCHAR(255) NOT NULL,
+				SIG             VARCHAR(255) NOT NULL,
+				DIMENSIONS      VARCHAR(255) NOT NULL,
+				REPO_VALUE      VARCHAR(255) NOT NULL,
+				SIG_VALUE       VARCHAR(255) NOT NULL,
+				SEVERITY_VALUE  VARCHAR(255) NOT NULL,
+				COMPONENT_VALUE VARCHAR(255) NOT NULL,
+				ASSIGNEE_VALUE  VARCHAR(255) NOT NULL,
+				START_TIME      DATETIME     NOT NULL,
+				END_TIME        DATETIME     NOT NULL,
+				DI              DOUBLE       NOT NULL,
+				CREATED_DI      DOUBLE       NOT NULL,
+				CLOSED_DI       DOUBLE       NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_GROUP_WINDOW (GROUP_HASH, START_TIME, END_TIME),
+				KEY IDX_REPO_SIG_DIMENSIONS (REPO, SIG, DIMENSIONS, START_TIME)
+			)`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "create ISSUE_EVENT written by storage and read by di",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS ISSUE_EVENT (
+				ISSUE_ID   BIGINT       NOT NULL,
+				SEQ        INT          NOT NULL,
+				TYPE       VARCHAR(64)  NOT NULL,
+				ACTOR      VARCHAR(255) NOT NULL,
+				LABEL      VARCHAR(255) NOT NULL,
+				CREATED_AT DATETIME     NOT NULL,
+				PRIMARY KEY (ISSUE_ID, SEQ),
+				KEY IDX_TYPE (TYPE)
+			)`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "create REOPENED_DI and add REOPENED_DI to DI_BREAKDOWN written by di",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS REOPENED_DI (
+				ID         BIGINT       NOT NULL AUTO_INCREMENT,
+				REPO       VARCHAR(255) NOT NULL,
+				SIG        VARCHAR(255) NOT NULL,
+				START_TIME DATETIME     NOT NULL,
+				END_TIME   DATETIME     NOT NULL,
+				DI         DOUBLE       NOT NULL,
+				PRIMARY KEY (ID),
+				UNIQUE KEY UK_REPO_SIG_WINDOW (REPO, SIG, START_TIME, END_TIME)
+			)`,
+		},
+		Steps: []func(db *sql.DB) error{
+			addColumn("DI_BREAKDOWN", "REOPENED_DI", "DOUBLE NOT NULL DEFAULT 0"),
+		},
+	},
+	{
+		Version:     7,
+		Description: "add the unique keys to DI, CREATED_DI, CLOSED_DI and coverage_timeline created before migrations",
+		Steps: []func(db *sql.DB) error{
+			addUniqueKey("DI", "UK_REPO_SIG_TIME", "REPO", "SIG", "TIME"),
+			addUniqueKey("CREATED_DI", "UK_REPO_SIG_WINDOW", "REPO", "SIG", "START_TIME", "END_TIME"),
+			addUniqueKey("CLOSED_DI", "UK_REPO_SIG_WINDOW", "REPO", "SIG", "START_TIME", "END_TIME"),
+			addUniqueKey("coverage_timeline", "uk_repo_id_time", "repo_id", "time"),
+		},
+	},
+}
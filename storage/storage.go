@@ -0,0 +1,273 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PingCAP-QE/libs/crawler"
+)
+
+// StoreIssues upserts the crawled issues into the tables created by migration.EnsureSchema in one transaction.
+// Issues are stored in the repository they are fetched from, or owner/name if the repository is not fetched.
+// Labels and assignees of an issue are replaced by the crawled ones, so removed labels are deleted.
+// Comments of an issue are replaced only if they are fetched, i.e. Comments is not nil.
+// Timeline events of an issue are replaced by the crawled ones of the types decoded in crawler.TimelineItem.
+// Storing the same issues again does not change the tables.
+func StoreIssues(db *sql.DB, owner, name string, issues []crawler.IssueWithComments) (err error) {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	repositories := make(map[string]int64)
+	for _, issue := range issues {
+		issueOwner, issueName := owner, name
+		if issue.Repository.Name != "" {
+			issueOwner, issueName = string(issue.Repository.Owner.Login), string(issue.Repository.Name)
+		}
+		key := issueOwner + "/" + issueName
+		repositoryID, ok := repositories[key]
+		if !ok {
+			if repositoryID, err = upsertRepository(tx, issueOwner, issueName); err != nil {
+				return err
+			}
+			repositories[key] = repositoryID
+		}
+
+		if err = upsertIssue(tx, repositoryID, issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertRepository inserts the repository if it does not exist and returns its ID.
+func upsertRepository(tx *sql.Tx, owner, name string) (int64, error) {
+	if _, err := tx.Exec(`INSERT IGNORE INTO REPOSITORY(OWNER, REPO_NAME) VALUES(?, ?)`, owner, name); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := tx.QueryRow(`SELECT ID FROM REPOSITORY WHERE OWNER = ? AND REPO_NAME = ?`, owner, name).Scan(&id)
+	return id, err
+}
+
+// upsertIssue upserts an issue with its labels, assignees, timeline events and comments.
+func upsertIssue(tx *sql.Tx, repositoryID int64, issue crawler.IssueWithComments) error {
+	issueID := int64(issue.DatabaseId)
+	_, err := tx.Exec(`INSERT INTO ISSUE(ID, NUMBER, REPOSITORY_ID, TITLE, BODY, AUTHOR, CLOSED, CLOSED_AT, CREATED_AT, UPDATED_AT)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE NUMBER = VALUES(NUMBER), REPOSITORY_ID = VALUES(REPOSITORY_ID),
+			TITLE = VALUES(TITLE), BODY = VALUES(BODY), AUTHOR = VALUES(AUTHOR), CLOSED = VALUES(CLOSED),
+			CLOSED_AT = VALUES(CLOSED_AT), CREATED_AT = VALUES(CREATED_AT), UPDATED_AT = VALUES(UPDATED_AT)`,
+		issueID, int(issue.Number), repositoryID, string(issue.Title), string(issue.Body), issue.Author.Login,
+		bool(issue.Closed), nullTime(issue.ClosedAt.Time), issue.CreatedAt.Time, nullTime(issue.UpdatedAt.Time))
+	if err != nil {
+		return err
+	}
+
+	if err := replaceLabels(tx, issueID, labelNames(issue.Issue)); err != nil {
+		return err
+	}
+	if err := replaceAssignees(tx, issueID, assigneeLogins(issue.Issue)); err != nil {
+		return err
+	}
+	if err := replaceEvents(tx, issueID, issue.TimelineItems.Nodes); err != nil {
+		return err
+	}
+	if issue.Comments != nil {
+		return replaceComments(tx, issueID, *issue.Comments)
+	}
+	return nil
+}
+
+// replaceLabels replaces the labels of an issue, the labels not exist are inserted.
+func replaceLabels(tx *sql.Tx, issueID int64, names []string) error {
+	labelIDs := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		if _, err := tx.Exec(`INSERT IGNORE INTO LABEL(NAME) VALUES(?)`, name); err != nil {
+			return err
+		}
+		var labelID int64
+		if err := tx.QueryRow(`SELECT ID FROM LABEL WHERE NAME = ?`, name).Scan(&labelID); err != nil {
+			return err
+		}
+		labelIDs = append(labelIDs, labelID)
+	}
+
+	query := `DELETE FROM LABEL_ISSUE_RELATIONSHIP WHERE ISSUE_ID = ?`
+	if len(labelIDs) > 0 {
+		query += ` AND LABEL_ID NOT IN (` + placeholders(len(labelIDs)) + `)`
+	}
+	if _, err := tx.Exec(query, append([]interface{}{issueID}, labelIDs...)...); err != nil {
+		return err
+	}
+	for _, labelID := range labelIDs {
+		if _, err := tx.Exec(`INSERT IGNORE INTO LABEL_ISSUE_RELATIONSHIP(ISSUE_ID, LABEL_ID) VALUES(?, ?)`, issueID, labelID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceAssignees replaces the assignees of an issue.
+func replaceAssignees(tx *sql.Tx, issueID int64, logins []string) error {
+	args := []interface{}{issueID}
+	query := `DELETE FROM ISSUE_ASSIGNEE WHERE ISSUE_ID = ?`
+	if len(logins) > 0 {
+		query += ` AND LOGIN NOT IN (` + placeholders(len(logins)) + `)`
+		for _, login := range logins {
+			args = append(args, login)
+		}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	for _, login := range logins {
+		if _, err := tx.Exec(`INSERT IGNORE INTO ISSUE_ASSIGNEE(ISSUE_ID, LOGIN) VALUES(?, ?)`, issueID, login); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceEvents replaces the timeline events of an issue, the items of types not decoded are skipped.
+// SEQ of an event is its position in the timeline.
+func replaceEvents(tx *sql.Tx, issueID int64, items []crawler.TimelineItem) error {
+	if _, err := tx.Exec(`DELETE FROM ISSUE_EVENT WHERE ISSUE_ID = ?`, issueID); err != nil {
+		return err
+	}
+	for seq, item := range items {
+		createdAt := item.CreatedAt()
+		if createdAt.IsZero() {
+			continue
+		}
+		actor, label := eventActorLabel(item)
+		_, err := tx.Exec(`INSERT INTO ISSUE_EVENT(ISSUE_ID, SEQ, TYPE, ACTOR, LABEL, CREATED_AT) VALUES(?, ?, ?, ?, ?, ?)`,
+			issueID, seq, item.Typename, actor, label, createdAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventActorLabel returns the actor of a timeline item, and the label name if it is a labeled or unlabeled event.
+func eventActorLabel(item crawler.TimelineItem) (string, string) {
+	switch item.Typename {
+	case crawler.CrossReferencedEventType:
+		return string(item.CrossReferencedEvent.Actor.Login), ""
+	case crawler.AssignedEventType:
+		return string(item.AssignedEvent.Actor.Login), ""
+	case crawler.UnassignedEventType:
+		return string(item.UnassignedEvent.Actor.Login), ""
+	case crawler.LabeledEventType:
+		return string(item.LabeledEvent.Actor.Login), string(item.LabeledEvent.Label.Name)
+	case crawler.UnlabeledEventType:
+		return string(item.UnlabeledEvent.Actor.Login), string(item.UnlabeledEvent.Label.Name)
+	case crawler.ClosedEventType:
+		return string(item.ClosedEvent.Actor.Login), ""
+	case crawler.ReopenedEventType:
+		return string(item.ReopenedEvent.Actor.Login), ""
+	case crawler.MilestonedEventType:
+		return string(item.MilestonedEvent.Actor.Login), ""
+	case crawler.DemilestonedEventType:
+		return string(item.DemilestonedEvent.Actor.Login), ""
+	case crawler.ConnectedEventType:
+		return string(item.ConnectedEvent.Actor.Login), ""
+	case crawler.TransferredEventType:
+		return string(item.TransferredEvent.Actor.Login), ""
+	}
+	return "", ""
+}
+
+// replaceComments upserts the comments of an issue and deletes the ones not crawled, which are deleted on GitHub.
+func replaceComments(tx *sql.Tx, issueID int64, comments []crawler.Comment) error {
+	args := []interface{}{issueID}
+	query := `DELETE FROM COMMENT WHERE ISSUE_ID = ?`
+	if len(comments) > 0 {
+		query += ` AND ID NOT IN (` + placeholders(len(comments)) + `)`
+		for _, comment := range comments {
+			args = append(args, int64(comment.DatabaseId))
+		}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		_, err := tx.Exec(`INSERT INTO COMMENT(ID, ISSUE_ID, AUTHOR, BODY, CREATED_AT, UPDATED_AT) VALUES(?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE ISSUE_ID = VALUES(ISSUE_ID), AUTHOR = VALUES(AUTHOR), BODY = VALUES(BODY),
+				CREATED_AT = VALUES(CREATED_AT), UPDATED_AT = VALUES(UPDATED_AT)`,
+			int64(comment.DatabaseId), issueID, comment.Author.Login, comment.Body,
+			nullTime(comment.CreatedAt.Time), nullTime(comment.UpdatedAt.Time))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelNames returns the sorted and deduplicated label names of an issue.
+func labelNames(issue crawler.Issue) []string {
+	names := make([]string, 0, len(issue.Labels.Nodes))
+	for _, label := range issue.Labels.Nodes {
+		names = append(names, string(label.Name))
+	}
+	return dedup(names)
+}
+
+// assigneeLogins returns the sorted and deduplicated assignee logins of an issue.
+func assigneeLogins(issue crawler.Issue) []string {
+	logins := make([]string, 0, len(issue.Assignees.Nodes))
+	for _, assignee := range issue.Assignees.Nodes {
+		logins = append(logins, string(assignee.Login))
+	}
+	return dedup(logins)
+}
+
+func dedup(values []string) []string {
+	sort.Strings(values)
+	result := values[:0]
+	for _, value := range values {
+		if len(result) == 0 || value != result[len(result)-1] {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// placeholders returns n comma separated placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// nullTime returns NULL for the zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
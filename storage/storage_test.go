@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP-QE libs Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PingCAP-QE/libs/crawler"
+	"github.com/PingCAP-QE/libs/internal/testdb"
+	"github.com/PingCAP-QE/libs/migration"
+	"github.com/shurcooL/githubv4"
+)
+
+// openTestDB opens the test database with the schema migrated, the test is skipped without it.
+func openTestDB(t *testing.T) *sql.DB {
+	db := testdb.Open(t)
+	if err := migration.EnsureSchema(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestIssue(labels ...string) crawler.IssueWithComments {
+	var issue crawler.IssueWithComments
+	issue.DatabaseId = 1000000001
+	issue.Number = 1
+	issue.Repository.Name = "test"
+	issue.Repository.Owner.Login = "test"
+	issue.Title = "test issue"
+	issue.CreatedAt = githubv4.DateTime{Time: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)}
+	for _, label := range labels {
+		issue.Labels.Nodes = append(issue.Labels.Nodes, struct{ Name githubv4.String }{githubv4.String(label)})
+	}
+	comments := []crawler.Comment{{DatabaseId: 2000000001, Body: "comment"}}
+	issue.Comments = &comments
+	return issue
+}
+
+func TestLabelNames(t *testing.T) {
+	names := labelNames(newTestIssue("type/bug", "sig/planner", "type/bug").Issue)
+	if !reflect.DeepEqual(names, []string{"sig/planner", "type/bug"}) {
+		t.Errorf("labelNames = %v", names)
+	}
+	if placeholders(3) != "?, ?, ?" {
+		t.Errorf("placeholders(3) = %s", placeholders(3))
+	}
+}
+
+func TestEventActorLabel(t *testing.T) {
+	var item crawler.TimelineItem
+	item.Typename = crawler.UnlabeledEventType
+	item.UnlabeledEvent.Actor.Login = "bot"
+	item.UnlabeledEvent.Label.Name = "severity/critical"
+	if actor, label := eventActorLabel(item); actor != "bot" || label != "severity/critical" {
+		t.Errorf("eventActorLabel = %s, %s", actor, label)
+	}
+}
+
+func TestStoreIssues(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	issue := newTestIssue("type/bug", "severity/major")
+	if err := StoreIssues(db, "", "", []crawler.IssueWithComments{issue, issue}); err != nil {
+		t.Fatal(err)
+	}
+	issue = newTestIssue("type/bug")
+	issue.Closed = true
+	issue.ClosedAt = githubv4.DateTime{Time: time.Date(2020, 9, 7, 0, 0, 0, 0, time.UTC)}
+	if err := StoreIssues(db, "", "", []crawler.IssueWithComments{issue}); err != nil {
+		t.Fatal(err)
+	}
+
+	var closed bool
+	var labels, comments int
+	if err := db.QueryRow(`SELECT CLOSED FROM ISSUE WHERE ID = ?`, issue.DatabaseId).Scan(&closed); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM LABEL_ISSUE_RELATIONSHIP WHERE ISSUE_ID = ?`, issue.DatabaseId).Scan(&labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM COMMENT WHERE ISSUE_ID = ?`, issue.DatabaseId).Scan(&comments); err != nil {
+		t.Fatal(err)
+	}
+	if !closed || labels != 1 || comments != 1 {
+		t.Errorf("closed = %v, labels = %d, comments = %d; expected true, 1, 1", closed, labels, comments)
+	}
+}